@@ -29,3 +29,11 @@ $CTL set_cluster_name rabbitmq@localhost
 # Enable shovel plugin
 $PLUGINS enable rabbitmq_shovel
 $PLUGINS enable rabbitmq_shovel_management
+
+# Enable federation plugin
+$PLUGINS enable rabbitmq_federation
+$PLUGINS enable rabbitmq_federation_management
+
+# Enable stream plugin
+$PLUGINS enable rabbitmq_stream
+$PLUGINS enable rabbitmq_stream_management
@@ -2,7 +2,10 @@ package rabbithole
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"net/url"
+	"strings"
 )
 
 //
@@ -40,6 +43,9 @@ type BindingInfo struct {
 
 // Returns all bindings
 func (c *Client) ListBindings() (rec []BindingInfo, err error) {
+	c, op := c.startOperation("ListBindings", "", "")
+	defer op.end(&err)
+
 	req, err := newGETRequest(c, "bindings/")
 	if err != nil {
 		return []BindingInfo{}, err
@@ -58,6 +64,9 @@ func (c *Client) ListBindings() (rec []BindingInfo, err error) {
 
 // Returns all bindings in a virtual host.
 func (c *Client) ListBindingsIn(vhost string) (rec []BindingInfo, err error) {
+	c, op := c.startOperation("ListBindingsIn", vhost, "")
+	defer op.end(&err)
+
 	req, err := newGETRequest(c, "bindings/"+PathEscape(vhost))
 	if err != nil {
 		return []BindingInfo{}, err
@@ -94,6 +103,9 @@ func (c *Client) ListBindingsIn(vhost string) (rec []BindingInfo, err error) {
 
 // Returns all bindings of individual queue.
 func (c *Client) ListQueueBindings(vhost, queue string) (rec []BindingInfo, err error) {
+	c, op := c.startOperation("ListQueueBindings", vhost, queue)
+	defer op.end(&err)
+
 	req, err := newGETRequest(c, "queues/"+PathEscape(vhost)+"/"+PathEscape(queue)+"/bindings")
 	if err != nil {
 		return []BindingInfo{}, err
@@ -106,14 +118,110 @@ func (c *Client) ListQueueBindings(vhost, queue string) (rec []BindingInfo, err
 	return rec, nil
 }
 
+// The management API addresses the default exchange, whose name is empty,
+// as amq.default.
+const defaultExchangePathName = "amq.default"
+
+func exchangePathSegment(exchange string) string {
+	if exchange == "" {
+		return defaultExchangePathName
+	}
+	return PathEscape(exchange)
+}
+
+//
+// GET /api/exchanges/{vhost}/{exchange}/bindings/source
+//
+
+// Returns all bindings having the exchange as source. An empty
+// exchange name refers to the default exchange.
+func (c *Client) ListExchangeBindingsWithSource(vhost, exchange string) (rec []BindingInfo, err error) {
+	c, op := c.startOperation("ListExchangeBindingsWithSource", vhost, exchange)
+	defer op.end(&err)
+
+	return c.listExchangeBindings(vhost, exchange, "source")
+}
+
+//
+// GET /api/exchanges/{vhost}/{exchange}/bindings/destination
+//
+
+// Returns all bindings having the exchange as destination.
+func (c *Client) ListExchangeBindingsWithDestination(vhost, exchange string) (rec []BindingInfo, err error) {
+	c, op := c.startOperation("ListExchangeBindingsWithDestination", vhost, exchange)
+	defer op.end(&err)
+
+	return c.listExchangeBindings(vhost, exchange, "destination")
+}
+
+func (c *Client) listExchangeBindings(vhost, exchange, sourceOrDestination string) (rec []BindingInfo, err error) {
+	req, err := newGETRequest(c, "exchanges/"+PathEscape(vhost)+"/"+exchangePathSegment(exchange)+"/bindings/"+sourceOrDestination)
+	if err != nil {
+		return []BindingInfo{}, err
+	}
+
+	if err = executeAndParseRequest(c, req, &rec); err != nil {
+		return []BindingInfo{}, err
+	}
+
+	return rec, nil
+}
+
+//
+// GET /api/bindings/{vhost}/e/{exchange}/q/{queue}
+//
+
+// Returns all bindings between an exchange and a queue. An empty
+// exchange name refers to the default exchange.
+func (c *Client) ListQueueBindingsBetween(vhost, exchange, queue string) (rec []BindingInfo, err error) {
+	c, op := c.startOperation("ListQueueBindingsBetween", vhost, exchange)
+	defer op.end(&err)
+
+	return c.listBindingsBetween(vhost, exchange, "q", queue)
+}
+
+//
+// GET /api/bindings/{vhost}/e/{source}/e/{destination}
+//
+
+// Returns all bindings between two exchanges.
+func (c *Client) ListExchangeBindingsBetween(vhost, source, destination string) (rec []BindingInfo, err error) {
+	c, op := c.startOperation("ListExchangeBindingsBetween", vhost, source)
+	defer op.end(&err)
+
+	return c.listBindingsBetween(vhost, source, "e", destination)
+}
+
+func (c *Client) listBindingsBetween(vhost, source, destinationType, destination string) (rec []BindingInfo, err error) {
+	req, err := newGETRequest(c, "bindings/"+PathEscape(vhost)+
+		"/e/"+exchangePathSegment(source)+"/"+destinationType+"/"+PathEscape(destination))
+	if err != nil {
+		return []BindingInfo{}, err
+	}
+
+	if err = executeAndParseRequest(c, req, &rec); err != nil {
+		return []BindingInfo{}, err
+	}
+
+	return rec, nil
+}
+
 //
 // POST /api/bindings/{vhost}/e/{source}/{destination_type}/{destination}
 //
 
 // DeclareBinding updates information about a binding between a source and a target
 func (c *Client) DeclareBinding(vhost string, info BindingInfo) (res *http.Response, err error) {
+	c, op := c.startOperation("DeclareBinding", vhost, info.Source)
+	defer op.end(&err)
+
 	info.Vhost = vhost
 
+	destinationType, err := bindingDestinationTypeSegment(info.DestinationType)
+	if err != nil {
+		return nil, err
+	}
+
 	if info.Arguments == nil {
 		info.Arguments = make(map[string]interface{})
 	}
@@ -123,7 +231,7 @@ func (c *Client) DeclareBinding(vhost string, info BindingInfo) (res *http.Respo
 	}
 
 	req, err := newRequestWithBody(c, "POST", "bindings/"+PathEscape(vhost)+
-		"/e/"+PathEscape(info.Source)+"/"+PathEscape(string(info.DestinationType[0]))+
+		"/e/"+PathEscape(info.Source)+"/"+destinationType+
 		"/"+PathEscape(info.Destination), body)
 
 	if err != nil {
@@ -138,14 +246,90 @@ func (c *Client) DeclareBinding(vhost string, info BindingInfo) (res *http.Respo
 	return res, nil
 }
 
+// DeclareQueueBinding binds a queue to an exchange. Returns the properties key
+// of the new binding, which identifies it in DeleteBinding.
+func (c *Client) DeclareQueueBinding(vhost, exchange, queue, routingKey string, arguments map[string]interface{}) (propertiesKey string, err error) {
+	c, op := c.startOperation("DeclareQueueBinding", vhost, exchange)
+	defer op.end(&err)
+
+	return c.declareBindingReturningPropertiesKey(vhost, BindingInfo{
+		Source:          exchange,
+		Destination:     queue,
+		DestinationType: "queue",
+		RoutingKey:      routingKey,
+		Arguments:       arguments,
+	})
+}
+
+// DeclareExchangeToExchangeBinding binds the destination exchange to the source exchange.
+// Returns the properties key of the new binding, which identifies it in DeleteBinding.
+func (c *Client) DeclareExchangeToExchangeBinding(vhost, source, destination, routingKey string, arguments map[string]interface{}) (propertiesKey string, err error) {
+	c, op := c.startOperation("DeclareExchangeToExchangeBinding", vhost, source)
+	defer op.end(&err)
+
+	return c.declareBindingReturningPropertiesKey(vhost, BindingInfo{
+		Source:          source,
+		Destination:     destination,
+		DestinationType: "exchange",
+		RoutingKey:      routingKey,
+		Arguments:       arguments,
+	})
+}
+
+func (c *Client) declareBindingReturningPropertiesKey(vhost string, info BindingInfo) (propertiesKey string, err error) {
+	res, err := c.DeclareBinding(vhost, info)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= http.StatusBadRequest {
+		return "", parseErrorResponse(res)
+	}
+
+	return propertiesKeyFromLocation(res.Header.Get("Location"))
+}
+
+// The Location header of a created binding ends with
+// {destination}/{propertiesKey}, the latter being percent-encoded.
+func propertiesKeyFromLocation(location string) (string, error) {
+	i := strings.LastIndex(location, "/")
+	if i < 0 {
+		return "", fmt.Errorf("cannot extract binding properties key from Location header %q", location)
+	}
+
+	return url.PathUnescape(location[i+1:])
+}
+
+// Binding paths use "q" for queue and "e" for exchange destinations.
+// The short forms are accepted as destination types, too.
+func bindingDestinationTypeSegment(destinationType string) (string, error) {
+	switch destinationType {
+	case "queue", "q":
+		return "q", nil
+	case "exchange", "e":
+		return "e", nil
+	}
+
+	return "", fmt.Errorf("invalid binding destination type %q: must be \"queue\" or \"exchange\"", destinationType)
+}
+
 //
 // DELETE /api/bindings/{vhost}/e/{source}/{destination_type}/{destination}/{props}
 //
 
 // DeleteBinding delets an individual binding
 func (c *Client) DeleteBinding(vhost string, info BindingInfo) (res *http.Response, err error) {
+	c, op := c.startOperation("DeleteBinding", vhost, info.Source)
+	defer op.end(&err)
+
+	destinationType, err := bindingDestinationTypeSegment(info.DestinationType)
+	if err != nil {
+		return nil, err
+	}
+
 	req, err := newRequestWithBody(c, "DELETE", "bindings/"+PathEscape(vhost)+
-		"/e/"+PathEscape(info.Source)+"/"+PathEscape(string(info.DestinationType[0]))+
+		"/e/"+PathEscape(info.Source)+"/"+destinationType+
 		"/"+PathEscape(info.Destination)+"/"+PathEscape(info.PropertiesKey), nil)
 	if err != nil {
 		return nil, err
@@ -53,6 +53,9 @@ type ChannelInfo struct {
 
 // Returns information about all open channels.
 func (c *Client) ListChannels() (rec []ChannelInfo, err error) {
+	c, op := c.startOperation("ListChannels", "", "")
+	defer op.end(&err)
+
 	req, err := newGETRequest(c, "channels")
 	if err != nil {
 		return []ChannelInfo{}, err
@@ -65,12 +68,57 @@ func (c *Client) ListChannels() (rec []ChannelInfo, err error) {
 	return rec, nil
 }
 
+//
+// GET /api/vhosts/{vhost}/channels
+//
+
+// Returns information about channels in a virtual host.
+func (c *Client) ListChannelsIn(vhost string) (rec []ChannelInfo, err error) {
+	c, op := c.startOperation("ListChannelsIn", vhost, "")
+	defer op.end(&err)
+
+	req, err := newGETRequest(c, "vhosts/"+PathEscape(vhost)+"/channels")
+	if err != nil {
+		return []ChannelInfo{}, err
+	}
+
+	if err = executeAndParseRequest(c, req, &rec); err != nil {
+		return []ChannelInfo{}, err
+	}
+
+	return rec, nil
+}
+
+//
+// GET /api/connections/{name}/channels
+//
+
+// Returns information about channels of a connection.
+func (c *Client) ListConnectionChannels(name string) (rec []ChannelInfo, err error) {
+	c, op := c.startOperation("ListConnectionChannels", "", name)
+	defer op.end(&err)
+
+	req, err := newGETRequest(c, "connections/"+PathEscape(name)+"/channels")
+	if err != nil {
+		return []ChannelInfo{}, err
+	}
+
+	if err = executeAndParseRequest(c, req, &rec); err != nil {
+		return []ChannelInfo{}, err
+	}
+
+	return rec, nil
+}
+
 //
 // GET /api/channels/{name}
 //
 
 // Returns channel information.
 func (c *Client) GetChannel(name string) (rec *ChannelInfo, err error) {
+	c, op := c.startOperation("GetChannel", "", name)
+	defer op.end(&err)
+
 	req, err := newGETRequest(c, "channels/"+PathEscape(name))
 	if err != nil {
 		return nil, err
@@ -2,7 +2,9 @@ package rabbithole
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
@@ -15,10 +17,17 @@ type Client struct {
 	// Username to use. This RabbitMQ user must have the "management" tag.
 	Username string
 	// Password to use.
-	Password  string
-	host      string
-	transport *http.Transport
-	timeout   time.Duration
+	Password    string
+	host        string
+	transport   *http.Transport
+	timeout     time.Duration
+	tokenSource TokenSource
+	credentials CredentialsProvider
+	middlewares []Middleware
+	logger      Logger
+	tracer      Tracer
+	throttle    *throttle
+	ctx         context.Context
 }
 
 func NewClient(uri string, username string, password string) (me *Client, err error) {
@@ -32,6 +41,7 @@ func NewClient(uri string, username string, password string) (me *Client, err er
 		host:     u.Host,
 		Username: username,
 		Password: password,
+		throttle: &throttle{},
 	}
 
 	return me, nil
@@ -50,6 +60,7 @@ func NewTLSClient(uri string, username string, password string, transport *http.
 		Username:  username,
 		Password:  password,
 		transport: transport,
+		throttle:  &throttle{},
 	}
 
 	return me, nil
@@ -66,54 +77,185 @@ func (c *Client) SetTimeout(timeout time.Duration) {
 	c.timeout = timeout
 }
 
+// SetTokenSource makes the Client authenticate with bearer tokens
+// (e.g. OAuth 2 access tokens) instead of Username and Password.
+// Tokens are reused until they are about to expire.
+func (c *Client) SetTokenSource(ts TokenSource) {
+	if ts == nil {
+		c.tokenSource = nil
+		return
+	}
+	c.tokenSource = ReuseTokenSource(ts)
+}
+
+// SetCredentialsProvider makes the Client fetch credentials from the provider
+// on every request instead of using Username and Password. When RabbitMQ
+// responds with a 401, credentials are fetched again and the request
+// is retried once. The retry counts towards rate limits and is seen
+// by middlewares like any other request.
+func (c *Client) SetCredentialsProvider(p CredentialsProvider) {
+	c.credentials = p
+}
+
+func setAuthorization(client *Client, req *http.Request) error {
+	if client.tokenSource == nil {
+		if client.credentials == nil {
+			req.SetBasicAuth(client.Username, client.Password)
+			return nil
+		}
+
+		c, err := client.credentials.Credentials()
+		if err != nil {
+			return err
+		}
+		req.SetBasicAuth(c.Username, c.Password)
+		return nil
+	}
+
+	t, err := client.tokenSource.Token()
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+t.AccessToken)
+
+	return nil
+}
+
 func newGETRequest(client *Client, path string) (*http.Request, error) {
 	s := client.Endpoint + "/api/" + path
 	req, err := http.NewRequest("GET", s, nil)
+	if err != nil {
+		return nil, err
+	}
+	if client.ctx != nil {
+		req = req.WithContext(client.ctx)
+	}
 
 	req.Close = true
-	req.SetBasicAuth(client.Username, client.Password)
+	if err = setAuthorization(client, req); err != nil {
+		return nil, err
+	}
 
 	// set Opaque to preserve the percent-encoded path. MK.
 	req.URL.Opaque = "//" + client.host + "/api/" + path
 
-	return req, err
+	return req, nil
 }
 
 func newGETRequestWithParameters(client *Client, path string, qs url.Values) (*http.Request, error) {
 	s := client.Endpoint + "/api/" + path + "?" + qs.Encode()
 
 	req, err := http.NewRequest("GET", s, nil)
+	if err != nil {
+		return nil, err
+	}
+	if client.ctx != nil {
+		req = req.WithContext(client.ctx)
+	}
+
 	req.Close = true
-	req.SetBasicAuth(client.Username, client.Password)
+	if err = setAuthorization(client, req); err != nil {
+		return nil, err
+	}
 
-	return req, err
+	return req, nil
 }
 
 func newRequestWithBody(client *Client, method string, path string, body []byte) (*http.Request, error) {
 	s := client.Endpoint + "/api/" + path
 
 	req, err := http.NewRequest(method, s, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if client.ctx != nil {
+		req = req.WithContext(client.ctx)
+	}
 
 	req.Close = true
-	req.SetBasicAuth(client.Username, client.Password)
+	if err = setAuthorization(client, req); err != nil {
+		return nil, err
+	}
 	// set Opaque to preserve the percent-encoded path.
 	req.URL.Opaque = "//" + client.host + "/api/" + path
 
 	req.Header.Add("Content-Type", "application/json")
 
-	return req, err
+	return req, nil
 }
 
 func executeRequest(client *Client, req *http.Request) (res *http.Response, err error) {
+	h := RequestHandler(func(req *http.Request) (*http.Response, error) {
+		return sendRequest(client, req)
+	})
+	if client.throttle != nil {
+		h = throttlingMiddleware(client.throttle)(h)
+	}
+	for i := len(client.middlewares) - 1; i >= 0; i-- {
+		h = client.middlewares[i](h)
+	}
+	if client.logger != nil {
+		h = loggingMiddleware(client.logger)(h)
+	}
+	if client.tracer != nil {
+		h = tracingMiddleware(client.tracer)(h)
+	}
+
+	res, err = h(req)
+	if err != nil || res.StatusCode != http.StatusUnauthorized || client.credentials == nil || client.tokenSource != nil {
+		return res, err
+	}
+
+	// credentials may have been rotated: fetch them again and retry once.
+	// The retry goes through middlewares and limits like any other request
+	res.Body.Close()
+	retry, err := newRetryRequest(client, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return h(retry)
+}
+
+func sendRequest(client *Client, req *http.Request) (res *http.Response, err error) {
 	httpc := &http.Client{
 		Timeout: client.timeout,
 	}
 	if client.transport != nil {
 		httpc.Transport = client.transport
 	}
+
 	return httpc.Do(req)
 }
 
+func newRetryRequest(client *Client, req *http.Request) (*http.Request, error) {
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return nil, errors.New("request body cannot be sent again")
+	}
+
+	if inv, ok := client.credentials.(CredentialsInvalidator); ok {
+		inv.InvalidateCredentials()
+	}
+
+	retry := *req
+	retry.Header = make(http.Header, len(req.Header))
+	for k, v := range req.Header {
+		retry.Header[k] = v
+	}
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		retry.Body = body
+	}
+	if err := setAuthorization(client, &retry); err != nil {
+		return nil, err
+	}
+
+	return &retry, nil
+}
+
 func executeAndParseRequest(client *Client, req *http.Request, rec interface{}) (err error) {
 	res, err := executeRequest(client, req)
 	if err != nil {
@@ -122,13 +264,7 @@ func executeAndParseRequest(client *Client, req *http.Request, rec interface{})
 	defer res.Body.Close() // always close body
 
 	if res.StatusCode >= http.StatusBadRequest {
-		rme := ErrorResponse{}
-		err = json.NewDecoder(res.Body).Decode(&rme)
-		if err != nil {
-			return fmt.Errorf("Error %d from RabbitMQ: %s", res.StatusCode, err)
-		}
-		rme.StatusCode = res.StatusCode
-		return rme
+		return parseErrorResponse(res)
 	}
 
 	err = json.NewDecoder(res.Body).Decode(&rec)
@@ -139,6 +275,18 @@ func executeAndParseRequest(client *Client, req *http.Request, rec interface{})
 	return nil
 }
 
+// parseErrorResponse decodes the body of an unsuccessful response
+// into an ErrorResponse.
+func parseErrorResponse(res *http.Response) error {
+	rme := ErrorResponse{}
+	err := json.NewDecoder(res.Body).Decode(&rme)
+	if err != nil {
+		return fmt.Errorf("Error %d from RabbitMQ: %s", res.StatusCode, err)
+	}
+	rme.StatusCode = res.StatusCode
+	return rme
+}
+
 // This is an ugly hack: we copy relevant bits from
 // https://github.com/golang/go/blob/7e2bf952a905f16a17099970392ea17545cdd193/src/net/url/url.go
 // because up to Go 1.8 there is no built-in method
@@ -10,6 +10,9 @@ type ClusterName struct {
 }
 
 func (c *Client) GetClusterName() (rec *ClusterName, err error) {
+	c, op := c.startOperation("GetClusterName", "", "")
+	defer op.end(&err)
+
 	req, err := newGETRequest(c, "cluster-name/")
 	if err != nil {
 		return nil, err
@@ -23,6 +26,9 @@ func (c *Client) GetClusterName() (rec *ClusterName, err error) {
 }
 
 func (c *Client) SetClusterName(cn ClusterName) (res *http.Response, err error) {
+	c, op := c.startOperation("SetClusterName", "", "")
+	defer op.end(&err)
+
 	body, err := json.Marshal(cn)
 	if err != nil {
 		return nil, err
@@ -1,6 +1,10 @@
 package rabbithole
 
-import "strconv"
+import (
+	"encoding/json"
+	"regexp"
+	"strconv"
+)
 
 // Extra arguments as a map (on queues, bindings, etc)
 type Properties map[string]interface{}
@@ -23,6 +27,25 @@ func (p *Port) UnmarshalJSON(b []byte) error {
 	return err
 }
 
+// URISet is a list of URIs. It can be unmarshalled from either a single URI or a list.
+type URISet []string
+
+// UnmarshalJSON accepts both a string and an array of strings
+func (s *URISet) UnmarshalJSON(b []byte) error {
+	var uri string
+	if err := json.Unmarshal(b, &uri); err == nil {
+		*s = URISet{uri}
+		return nil
+	}
+
+	var uris []string
+	if err := json.Unmarshal(b, &uris); err != nil {
+		return err
+	}
+	*s = URISet(uris)
+	return nil
+}
+
 // RateDetailSample single touple
 type RateDetailSample struct {
 	Sample    int64 `json:"sample"`
@@ -64,3 +87,11 @@ type MessageStats struct {
 	Ack                 int64       `json:"ack"`
 	AckDetails          RateDetails `json:"ack_details"`
 }
+
+// Compiles a pattern used by RabbitMQ to match names, e.g. in policies
+// and permissions. Like on the server, patterns are not anchored.
+// Go regular expressions differ from the server's PCRE ones in
+// a few rarely used features (e.g. backreferences).
+func compileBrokerPattern(pattern string) (*regexp.Regexp, error) {
+	return regexp.Compile(pattern)
+}
@@ -82,6 +82,9 @@ type ConnectionInfo struct {
 //
 
 func (c *Client) ListConnections() (rec []ConnectionInfo, err error) {
+	c, op := c.startOperation("ListConnections", "", "")
+	defer op.end(&err)
+
 	req, err := newGETRequest(c, "connections")
 	if err != nil {
 		return []ConnectionInfo{}, err
@@ -94,11 +97,35 @@ func (c *Client) ListConnections() (rec []ConnectionInfo, err error) {
 	return rec, nil
 }
 
+//
+// GET /api/vhosts/{vhost}/connections
+//
+
+// Returns information about connections in a virtual host.
+func (c *Client) ListConnectionsIn(vhost string) (rec []ConnectionInfo, err error) {
+	c, op := c.startOperation("ListConnectionsIn", vhost, "")
+	defer op.end(&err)
+
+	req, err := newGETRequest(c, "vhosts/"+PathEscape(vhost)+"/connections")
+	if err != nil {
+		return []ConnectionInfo{}, err
+	}
+
+	if err = executeAndParseRequest(c, req, &rec); err != nil {
+		return []ConnectionInfo{}, err
+	}
+
+	return rec, nil
+}
+
 //
 // GET /api/connections/{name}
 //
 
 func (c *Client) GetConnection(name string) (rec *ConnectionInfo, err error) {
+	c, op := c.startOperation("GetConnection", "", name)
+	defer op.end(&err)
+
 	req, err := newGETRequest(c, "connections/"+PathEscape(name))
 	if err != nil {
 		return nil, err
@@ -111,15 +138,78 @@ func (c *Client) GetConnection(name string) (rec *ConnectionInfo, err error) {
 	return rec, nil
 }
 
+//
+// GET /api/connections/username/{username}
+//
+
+// Returns information about connections of a user.
+func (c *Client) ListConnectionsOfUser(username string) (rec []ConnectionInfo, err error) {
+	c, op := c.startOperation("ListConnectionsOfUser", "", username)
+	defer op.end(&err)
+
+	req, err := newGETRequest(c, "connections/username/"+PathEscape(username))
+	if err != nil {
+		return []ConnectionInfo{}, err
+	}
+
+	if err = executeAndParseRequest(c, req, &rec); err != nil {
+		return []ConnectionInfo{}, err
+	}
+
+	return rec, nil
+}
+
 //
 // DELETE /api/connections/{name}
 //
 
+// Closes a connection.
 func (c *Client) CloseConnection(name string) (res *http.Response, err error) {
+	c, op := c.startOperation("CloseConnection", "", name)
+	defer op.end(&err)
+
+	return c.closeConnection(name, "")
+}
+
+// Closes a connection, passing the given reason on to the client
+// (via the X-Reason header). An empty reason uses the server default.
+func (c *Client) CloseConnectionWithReason(name string, reason string) (res *http.Response, err error) {
+	c, op := c.startOperation("CloseConnectionWithReason", "", name)
+	defer op.end(&err)
+
+	return c.closeConnection(name, reason)
+}
+
+func (c *Client) closeConnection(name string, reason string) (res *http.Response, err error) {
 	req, err := newRequestWithBody(c, "DELETE", "connections/"+PathEscape(name), nil)
 	if err != nil {
 		return nil, err
 	}
+	setReasonHeader(req, reason)
+
+	res, err = executeRequest(c, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+//
+// DELETE /api/connections/username/{username}
+//
+
+// Closes all connections of a user, passing the given reason on to the clients
+// (via the X-Reason header). An empty reason uses the server default.
+func (c *Client) CloseAllConnectionsOfUser(username string, reason string) (res *http.Response, err error) {
+	c, op := c.startOperation("CloseAllConnectionsOfUser", "", username)
+	defer op.end(&err)
+
+	req, err := newRequestWithBody(c, "DELETE", "connections/username/"+PathEscape(username), nil)
+	if err != nil {
+		return nil, err
+	}
+	setReasonHeader(req, reason)
 
 	res, err = executeRequest(c, req)
 	if err != nil {
@@ -128,3 +218,9 @@ func (c *Client) CloseConnection(name string) (res *http.Response, err error) {
 
 	return res, nil
 }
+
+func setReasonHeader(req *http.Request, reason string) {
+	if reason != "" {
+		req.Header.Set("X-Reason", reason)
+	}
+}
@@ -0,0 +1,121 @@
+package rabbithole
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+)
+
+// Credentials used for HTTP basic authentication.
+type Credentials struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// CredentialsProvider is consulted for credentials on every request,
+// so that rotated credentials are picked up without recreating clients.
+// See Client.SetCredentialsProvider.
+type CredentialsProvider interface {
+	Credentials() (Credentials, error)
+}
+
+// CredentialsInvalidator can be implemented by providers that cache
+// credentials. InvalidateCredentials is called when RabbitMQ rejects
+// the credentials, before they are fetched again.
+type CredentialsInvalidator interface {
+	InvalidateCredentials()
+}
+
+type staticCredentialsProvider struct {
+	credentials Credentials
+}
+
+// NewStaticCredentialsProvider returns a provider that always returns the same credentials.
+func NewStaticCredentialsProvider(username, password string) CredentialsProvider {
+	return staticCredentialsProvider{Credentials{Username: username, Password: password}}
+}
+
+func (p staticCredentialsProvider) Credentials() (Credentials, error) {
+	return p.credentials, nil
+}
+
+// EnvCredentialsProvider reads credentials from environment variables.
+type EnvCredentialsProvider struct {
+	UsernameVariable string
+	PasswordVariable string
+}
+
+// NewEnvCredentialsProvider returns a provider that reads credentials from
+// the given environment variables, e.g. RABBITMQ_USERNAME and RABBITMQ_PASSWORD.
+func NewEnvCredentialsProvider(usernameVariable, passwordVariable string) *EnvCredentialsProvider {
+	return &EnvCredentialsProvider{UsernameVariable: usernameVariable, PasswordVariable: passwordVariable}
+}
+
+func (p *EnvCredentialsProvider) Credentials() (Credentials, error) {
+	username, ok := os.LookupEnv(p.UsernameVariable)
+	if !ok {
+		return Credentials{}, fmt.Errorf("environment variable %s is not set", p.UsernameVariable)
+	}
+	password, ok := os.LookupEnv(p.PasswordVariable)
+	if !ok {
+		return Credentials{}, fmt.Errorf("environment variable %s is not set", p.PasswordVariable)
+	}
+
+	return Credentials{Username: username, Password: password}, nil
+}
+
+// FileCredentialsProvider reads credentials from a JSON file such as
+//
+//	{"username": "guest", "password": "guest"}
+//
+// The file is read again whenever its modification time or size changes,
+// and when RabbitMQ rejects the credentials.
+type FileCredentialsProvider struct {
+	path string
+
+	mu          sync.Mutex
+	modTime     time.Time
+	size        int64
+	credentials *Credentials
+}
+
+// NewFileCredentialsProvider returns a provider that reads credentials from path.
+func NewFileCredentialsProvider(path string) *FileCredentialsProvider {
+	return &FileCredentialsProvider{path: path}
+}
+
+func (p *FileCredentialsProvider) Credentials() (Credentials, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	fi, err := os.Stat(p.path)
+	if err != nil {
+		return Credentials{}, err
+	}
+	if p.credentials != nil && fi.ModTime().Equal(p.modTime) && fi.Size() == p.size {
+		return *p.credentials, nil
+	}
+
+	data, err := ioutil.ReadFile(p.path)
+	if err != nil {
+		return Credentials{}, err
+	}
+	var c Credentials
+	if err = json.Unmarshal(data, &c); err != nil {
+		return Credentials{}, fmt.Errorf("could not parse credentials file %s: %s", p.path, err)
+	}
+
+	p.credentials, p.modTime, p.size = &c, fi.ModTime(), fi.Size()
+
+	return c, nil
+}
+
+func (p *FileCredentialsProvider) InvalidateCredentials() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.credentials = nil
+}
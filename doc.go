@@ -26,6 +26,10 @@ Operations on Connections
         xs, err := rmqc.ListConnections()
         // => []ConnectionInfo, err
 
+        // list connections in a vhost
+        xs, err := rmqc.ListConnectionsIn("/")
+        // => []ConnectionInfo, err
+
         conn, err := rmqc.GetConnection("127.0.0.1:50545 -> 127.0.0.1:5672")
         // => ConnectionInfo, err
 
@@ -33,11 +37,31 @@ Operations on Connections
         _, err := rmqc.CloseConnection("127.0.0.1:50545 -> 127.0.0.1:5672")
         // => *http.Response, err
 
+        // Forcefully close connection, telling the client why
+        _, err := rmqc.CloseConnectionWithReason("127.0.0.1:50545 -> 127.0.0.1:5672", "rotating credentials")
+        // => *http.Response, err
+
+        // list connections of a user
+        xs, err := rmqc.ListConnectionsOfUser("my.user")
+        // => []ConnectionInfo, err
+
+        // Forcefully close all connections of a user
+        _, err := rmqc.CloseAllConnectionsOfUser("my.user", "rotating credentials")
+        // => *http.Response, err
+
 Operations on Channels
 
         xs, err := rmqc.ListChannels()
         // => []ChannelInfo, err
 
+        // list channels in a vhost
+        xs, err := rmqc.ListChannelsIn("/")
+        // => []ChannelInfo, err
+
+        // list channels of a connection
+        xs, err := rmqc.ListConnectionChannels("127.0.0.1:50545 -> 127.0.0.1:5672")
+        // => []ChannelInfo, err
+
         ch, err := rmqc.GetChannel("127.0.0.1:50545 -> 127.0.0.1:5672 (1)")
         // => ChannelInfo, err
 
@@ -79,6 +103,30 @@ Operations on Queues
         resp, err := rmqc.DeclareQueue("/", "a.queue", QueueSettings{Durable: false})
         // => *http.Response, err
 
+        // declares a queue with well-known optional arguments
+        args, err := NewQueueArguments().MessageTTL(30 * time.Second).MaxLength(10000).DeadLetterExchange("dlx").Build()
+        // => map[string]interface{}, err
+        resp, err := rmqc.DeclareQueue("/", "a.queue", QueueSettings{Durable: true, Arguments: args})
+        // => *http.Response, err
+
+        // declares a quorum queue
+        resp, err := rmqc.DeclareQueue("/", "a.quorum.queue", QueueSettings{Type: QueueTypeQuorum, Durable: true})
+        // => *http.Response, err
+
+        // adds or removes a quorum queue replica
+        resp, err := rmqc.AddQuorumQueueReplica("/", "a.quorum.queue", "rabbit@hostname")
+        // => *http.Response, err
+        resp, err := rmqc.DeleteQuorumQueueReplica("/", "a.quorum.queue", "rabbit@hostname")
+        // => *http.Response, err
+
+        // adds a replica on a node to all matching quorum queues
+        resp, err := rmqc.GrowQuorumQueueReplicas("rabbit@hostname", QuorumQueueGrowSettings{VhostPattern: ".*", QueuePattern: ".*", Strategy: GrowAll})
+        // => *http.Response, err
+
+        // removes all quorum queue replicas from a node
+        resp, err := rmqc.ShrinkQuorumQueueReplicas("rabbit@hostname")
+        // => *http.Response, err
+
         // deletes individual queue
         resp, err := rmqc.DeleteQueue("/", "a.queue")
         // => *http.Response, err
@@ -100,6 +148,22 @@ Operations on Bindings
         bs, err := rmqc.ListQueueBindings("/", "a.queue")
         // => []BindingInfo, err
 
+        // list bindings with an exchange as source
+        bs, err := rmqc.ListExchangeBindingsWithSource("/", "an.exchange")
+        // => []BindingInfo, err
+
+        // list bindings with an exchange as destination
+        bs, err := rmqc.ListExchangeBindingsWithDestination("/", "an.exchange")
+        // => []BindingInfo, err
+
+        // list bindings between an exchange and a queue
+        bs, err := rmqc.ListQueueBindingsBetween("/", "an.exchange", "a.queue")
+        // => []BindingInfo, err
+
+        // list bindings between two exchanges
+        bs, err := rmqc.ListExchangeBindingsBetween("/", "an.exchange", "another.exchange")
+        // => []BindingInfo, err
+
         // declare a binding
         resp, err := rmqc.DeclareBinding("/", BindingInfo{
             Source: "an.exchange",
@@ -109,6 +173,14 @@ Operations on Bindings
         })
         // => *http.Response, err
 
+        // declare a binding of a queue to an exchange, returning its properties key
+        pk, err := rmqc.DeclareQueueBinding("/", "an.exchange", "a.queue", "#", nil)
+        // => string, err
+
+        // declare a binding of an exchange to another exchange, returning its properties key
+        pk, err := rmqc.DeclareExchangeToExchangeBinding("/", "an.exchange", "another.exchange", "#", nil)
+        // => string, err
+
         // deletes individual binding
         resp, err := rmqc.DeleteBinding("/", BindingInfo{
             Source: "an.exchange",
@@ -174,6 +246,34 @@ Managing Permissions
         resp, err := rmqc.ClearPermissionsIn("/", "my.user")
         // => *http.Response, err
 
+Health Checks
+
+        // runs the aliveness test in a vhost
+        res, err := rmqc.Aliveness("/")
+        // => *AlivenessTestStatus, err
+
+        // runs the aliveness test in all vhosts, returning the failing ones
+        fs, err := rmqc.CheckAllVhosts()
+        // => []VhostAlivenessFailure, err
+
+        // same, running up to 8 aliveness tests at a time (4 by default)
+        fs, err := rmqc.CheckAllVhostsWithConcurrency(8)
+        // => []VhostAlivenessFailure, err
+
+        // a failed check is returned as a result with a "failed" status,
+        // not as an error
+        res, err := rmqc.CheckAlarms()
+        // => AlarmsHealthCheckResult, err
+
+        res, err := rmqc.CheckCertificateExpiration(4, TimeUnitWeeks)
+        // => CertificateExpirationHealthCheckResult, err
+
+        res, err := rmqc.CheckPortListener(5672)
+        // => PortListenerHealthCheckResult, err
+
+        res, err := rmqc.CheckProtocolListener("amqp")
+        // => ProtocolListenerHealthCheckResult, err
+
 Operations on cluster name
         // Get cluster name
         cn, err := rmqc.GetClusterName()
@@ -37,6 +37,9 @@ type ExchangeSettings struct {
 }
 
 func (c *Client) ListExchanges() (rec []ExchangeInfo, err error) {
+	c, op := c.startOperation("ListExchanges", "", "")
+	defer op.end(&err)
+
 	req, err := newGETRequest(c, "exchanges")
 	if err != nil {
 		return []ExchangeInfo{}, err
@@ -54,6 +57,9 @@ func (c *Client) ListExchanges() (rec []ExchangeInfo, err error) {
 //
 
 func (c *Client) ListExchangesIn(vhost string) (rec []ExchangeInfo, err error) {
+	c, op := c.startOperation("ListExchangesIn", vhost, "")
+	defer op.end(&err)
+
 	req, err := newGETRequest(c, "exchanges/"+PathEscape(vhost))
 	if err != nil {
 		return []ExchangeInfo{}, err
@@ -161,6 +167,9 @@ type DetailedExchangeInfo struct {
 }
 
 func (c *Client) GetExchange(vhost, exchange string) (rec *DetailedExchangeInfo, err error) {
+	c, op := c.startOperation("GetExchange", vhost, exchange)
+	defer op.end(&err)
+
 	req, err := newGETRequest(c, "exchanges/"+PathEscape(vhost)+"/"+PathEscape(exchange))
 	if err != nil {
 		return nil, err
@@ -178,6 +187,9 @@ func (c *Client) GetExchange(vhost, exchange string) (rec *DetailedExchangeInfo,
 //
 
 func (c *Client) DeclareExchange(vhost, exchange string, info ExchangeSettings) (res *http.Response, err error) {
+	c, op := c.startOperation("DeclareExchange", vhost, exchange)
+	defer op.end(&err)
+
 	if info.Arguments == nil {
 		info.Arguments = make(map[string]interface{})
 	}
@@ -204,6 +216,9 @@ func (c *Client) DeclareExchange(vhost, exchange string, info ExchangeSettings)
 //
 
 func (c *Client) DeleteExchange(vhost, exchange string) (res *http.Response, err error) {
+	c, op := c.startOperation("DeleteExchange", vhost, exchange)
+	defer op.end(&err)
+
 	req, err := newRequestWithBody(c, "DELETE", "exchanges/"+PathEscape(vhost)+"/"+PathEscape(exchange), nil)
 	if err != nil {
 		return nil, err
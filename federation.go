@@ -9,29 +9,120 @@ import (
 // added to the entities (queues, exchanges or both)
 // that match a policy.
 type FederationDefinition struct {
-	Uri            string `json:"uri"`
-	Expires        int    `json:"expires"`
-	MessageTTL     int32  `json:"message-ttl"`
-	MaxHops        int    `json:"max-hops"`
-	PrefetchCount  int    `json:"prefetch-count"`
-	ReconnectDelay int    `json:"reconnect-delay"`
-	AckMode        string `json:"ack-mode,omitempty"`
-	TrustUserId    bool   `json:"trust-user-id"`
-	Exchange       string `json:"exchange"`
-	Queue          string `json:"queue"`
+	// One or more upstream URIs. When there is more than one, a random one is picked
+	Uri URISet `json:"uri"`
+	// Expiry time (in milliseconds) of the upstream queue after disconnection
+	Expires *int `json:"expires,omitempty"`
+	// Expiry time (in milliseconds) of messages in the upstream queue
+	MessageTTL *int32 `json:"message-ttl,omitempty"`
+	// Maximum number of federation links a message can traverse
+	MaxHops int `json:"max-hops,omitempty"`
+	// Maximum number of unacknowledged messages
+	PrefetchCount int `json:"prefetch-count,omitempty"`
+	// Seconds to wait before reconnecting after a failure
+	ReconnectDelay int `json:"reconnect-delay,omitempty"`
+	// Acknowledgement mode: "on-confirm", "on-publish" or "no-ack"
+	AckMode string `json:"ack-mode,omitempty"`
+	// Preserve the user-id of forwarded messages
+	TrustUserId bool `json:"trust-user-id"`
+	// Name of the upstream exchange, defaults to the federated exchange name
+	Exchange string `json:"exchange,omitempty"`
+	// Name of the upstream queue, defaults to the federated queue name
+	Queue string `json:"queue,omitempty"`
+	// Protocol used by queue federation links
+	QueueProtocol string `json:"queue-protocol,omitempty"`
+	// Consumer tag used by queue federation links
+	ConsumerTag string `json:"consumer-tag,omitempty"`
+	// Bind without waiting for confirmation (exchange federation)
+	BindNoWait bool `json:"bind-nowait,omitempty"`
+	// Value of x-ha-policy of the upstream queue (exchange federation)
+	HaPolicy string `json:"ha-policy,omitempty"`
 }
 
 // Represents a configured Federation upstream.
 type FederationUpstream struct {
+	// Upstream name
+	Name string `json:"name,omitempty"`
+	// Virtual host this upstream belongs to
+	Vhost string `json:"vhost,omitempty"`
+	// Component upstreams belong to
+	Component string `json:"component,omitempty"`
+
 	Definition FederationDefinition `json:"value"`
 }
 
+//
+// GET /api/parameters/federation-upstream
+//
+
+// Returns all federation upstreams.
+func (c *Client) ListFederationUpstreams() (rec []FederationUpstream, err error) {
+	c, op := c.startOperation("ListFederationUpstreams", "", "")
+	defer op.end(&err)
+
+	req, err := newGETRequest(c, "parameters/federation-upstream")
+	if err != nil {
+		return []FederationUpstream{}, err
+	}
+
+	if err = executeAndParseRequest(c, req, &rec); err != nil {
+		return []FederationUpstream{}, err
+	}
+
+	return rec, nil
+}
+
+//
+// GET /api/parameters/federation-upstream/{vhost}
+//
+
+// Returns all federation upstreams in a virtual host.
+func (c *Client) ListFederationUpstreamsIn(vhost string) (rec []FederationUpstream, err error) {
+	c, op := c.startOperation("ListFederationUpstreamsIn", vhost, "")
+	defer op.end(&err)
+
+	req, err := newGETRequest(c, "parameters/federation-upstream/"+PathEscape(vhost))
+	if err != nil {
+		return []FederationUpstream{}, err
+	}
+
+	if err = executeAndParseRequest(c, req, &rec); err != nil {
+		return []FederationUpstream{}, err
+	}
+
+	return rec, nil
+}
+
+//
+// GET /api/parameters/federation-upstream/{vhost}/{upstream}
+//
+
+// Returns a federation upstream.
+func (c *Client) GetFederationUpstream(vhost, upstreamName string) (rec *FederationUpstream, err error) {
+	c, op := c.startOperation("GetFederationUpstream", vhost, upstreamName)
+	defer op.end(&err)
+
+	req, err := newGETRequest(c, "parameters/federation-upstream/"+PathEscape(vhost)+"/"+PathEscape(upstreamName))
+	if err != nil {
+		return nil, err
+	}
+
+	if err = executeAndParseRequest(c, req, &rec); err != nil {
+		return nil, err
+	}
+
+	return rec, nil
+}
+
 //
 // PUT /api/parameters/federation-upstream/{vhost}/{upstream}
 //
 
 // Updates a federation upstream
 func (c *Client) PutFederationUpstream(vhost string, upstreamName string, fDef FederationDefinition) (res *http.Response, err error) {
+	c, op := c.startOperation("PutFederationUpstream", vhost, upstreamName)
+	defer op.end(&err)
+
 	fedUp := FederationUpstream{
 		Definition: fDef,
 	}
@@ -59,6 +150,9 @@ func (c *Client) PutFederationUpstream(vhost string, upstreamName string, fDef F
 
 // Deletes a federation upstream.
 func (c *Client) DeleteFederationUpstream(vhost, upstreamName string) (res *http.Response, err error) {
+	c, op := c.startOperation("DeleteFederationUpstream", vhost, upstreamName)
+	defer op.end(&err)
+
 	req, err := newRequestWithBody(c, "DELETE", "parameters/federation-upstream/"+PathEscape(vhost)+"/"+PathEscape(upstreamName), nil)
 	if err != nil {
 		return nil, err
@@ -71,3 +165,225 @@ func (c *Client) DeleteFederationUpstream(vhost, upstreamName string) (res *http
 
 	return res, nil
 }
+
+// State of a federation link.
+type FederationLinkStatus string
+
+const (
+	FederationLinkStarting FederationLinkStatus = "starting"
+	FederationLinkRunning  FederationLinkStatus = "running"
+	FederationLinkError    FederationLinkStatus = "error"
+	FederationLinkShutdown FederationLinkStatus = "shutdown"
+)
+
+// Represents a link from a federated exchange or queue to its upstream.
+type FederationLink struct {
+	// Node the link runs on
+	Node string `json:"node"`
+	// Virtual host of the federated exchange or queue
+	Vhost string `json:"vhost"`
+	// Link identifier
+	ID string `json:"id"`
+	// Upstream name
+	Upstream string `json:"upstream"`
+	// What is federated: "exchange" or "queue"
+	Type string `json:"type"`
+	// Federated exchange, if Type is "exchange"
+	Exchange string `json:"exchange,omitempty"`
+	// Exchange on the upstream, if Type is "exchange"
+	UpstreamExchange string `json:"upstream_exchange,omitempty"`
+	// Federated queue, if Type is "queue"
+	Queue string `json:"queue,omitempty"`
+	// Queue on the upstream, if Type is "queue"
+	UpstreamQueue string `json:"upstream_queue,omitempty"`
+	// Link status
+	Status FederationLinkStatus `json:"status"`
+	// Error text, if Status is "error"
+	Error string `json:"error,omitempty"`
+	// URI of the upstream (with credentials removed)
+	URI string `json:"uri"`
+	// Name of the local connection used by the link
+	LocalConnection string `json:"local_connection,omitempty"`
+	// When the status was last updated
+	Timestamp string `json:"timestamp"`
+}
+
+//
+// GET /api/federation-links
+//
+
+// Returns status of all federation links.
+func (c *Client) ListFederationLinks() (rec []FederationLink, err error) {
+	c, op := c.startOperation("ListFederationLinks", "", "")
+	defer op.end(&err)
+
+	req, err := newGETRequest(c, "federation-links")
+	if err != nil {
+		return []FederationLink{}, err
+	}
+
+	if err = executeAndParseRequest(c, req, &rec); err != nil {
+		return []FederationLink{}, err
+	}
+
+	return rec, nil
+}
+
+//
+// GET /api/federation-links/{vhost}
+//
+
+// Returns status of federation links in a virtual host.
+func (c *Client) ListFederationLinksIn(vhost string) (rec []FederationLink, err error) {
+	c, op := c.startOperation("ListFederationLinksIn", vhost, "")
+	defer op.end(&err)
+
+	req, err := newGETRequest(c, "federation-links/"+PathEscape(vhost))
+	if err != nil {
+		return []FederationLink{}, err
+	}
+
+	if err = executeAndParseRequest(c, req, &rec); err != nil {
+		return []FederationLink{}, err
+	}
+
+	return rec, nil
+}
+
+// A member of a federation upstream set: an upstream
+// and, optionally, upstream settings it overrides.
+type FederationUpstreamSetMember struct {
+	// Upstream name
+	Upstream string `json:"upstream"`
+	// Name of the upstream exchange, overrides the upstream setting
+	Exchange string `json:"exchange,omitempty"`
+	// Name of the upstream queue, overrides the upstream setting
+	Queue string `json:"queue,omitempty"`
+}
+
+// Represents a configured federation upstream set.
+type FederationUpstreamSet struct {
+	// Upstream set name
+	Name string `json:"name,omitempty"`
+	// Virtual host this upstream set belongs to
+	Vhost string `json:"vhost,omitempty"`
+	// Component upstream sets belong to
+	Component string `json:"component,omitempty"`
+
+	Definition []FederationUpstreamSetMember `json:"value"`
+}
+
+//
+// GET /api/parameters/federation-upstream-set
+//
+
+// Returns all federation upstream sets.
+func (c *Client) ListFederationUpstreamSets() (rec []FederationUpstreamSet, err error) {
+	c, op := c.startOperation("ListFederationUpstreamSets", "", "")
+	defer op.end(&err)
+
+	req, err := newGETRequest(c, "parameters/federation-upstream-set")
+	if err != nil {
+		return []FederationUpstreamSet{}, err
+	}
+
+	if err = executeAndParseRequest(c, req, &rec); err != nil {
+		return []FederationUpstreamSet{}, err
+	}
+
+	return rec, nil
+}
+
+//
+// GET /api/parameters/federation-upstream-set/{vhost}
+//
+
+// Returns all federation upstream sets in a virtual host.
+func (c *Client) ListFederationUpstreamSetsIn(vhost string) (rec []FederationUpstreamSet, err error) {
+	c, op := c.startOperation("ListFederationUpstreamSetsIn", vhost, "")
+	defer op.end(&err)
+
+	req, err := newGETRequest(c, "parameters/federation-upstream-set/"+PathEscape(vhost))
+	if err != nil {
+		return []FederationUpstreamSet{}, err
+	}
+
+	if err = executeAndParseRequest(c, req, &rec); err != nil {
+		return []FederationUpstreamSet{}, err
+	}
+
+	return rec, nil
+}
+
+//
+// GET /api/parameters/federation-upstream-set/{vhost}/{name}
+//
+
+// Returns a federation upstream set.
+func (c *Client) GetFederationUpstreamSet(vhost, setName string) (rec *FederationUpstreamSet, err error) {
+	c, op := c.startOperation("GetFederationUpstreamSet", vhost, setName)
+	defer op.end(&err)
+
+	req, err := newGETRequest(c, "parameters/federation-upstream-set/"+PathEscape(vhost)+"/"+PathEscape(setName))
+	if err != nil {
+		return nil, err
+	}
+
+	if err = executeAndParseRequest(c, req, &rec); err != nil {
+		return nil, err
+	}
+
+	return rec, nil
+}
+
+//
+// PUT /api/parameters/federation-upstream-set/{vhost}/{name}
+//
+
+// Updates a federation upstream set.
+func (c *Client) PutFederationUpstreamSet(vhost string, setName string, members []FederationUpstreamSetMember) (res *http.Response, err error) {
+	c, op := c.startOperation("PutFederationUpstreamSet", vhost, setName)
+	defer op.end(&err)
+
+	set := FederationUpstreamSet{
+		Definition: members,
+	}
+	body, err := json.Marshal(set)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := newRequestWithBody(c, "PUT", "parameters/federation-upstream-set/"+PathEscape(vhost)+"/"+PathEscape(setName), body)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err = executeRequest(c, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+//
+// DELETE /api/parameters/federation-upstream-set/{vhost}/{name}
+//
+
+// Deletes a federation upstream set.
+func (c *Client) DeleteFederationUpstreamSet(vhost, setName string) (res *http.Response, err error) {
+	c, op := c.startOperation("DeleteFederationUpstreamSet", vhost, setName)
+	defer op.end(&err)
+
+	req, err := newRequestWithBody(c, "DELETE", "parameters/federation-upstream-set/"+PathEscape(vhost)+"/"+PathEscape(setName), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err = executeRequest(c, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
@@ -1,5 +1,14 @@
 package rabbithole
 
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+)
+
 type HealthCheckStatus struct {
 	Status string `json:"status"`
 	Reason string `json:"reason"`
@@ -22,6 +31,9 @@ func (hcs *HealthCheckStatus) Ok() bool {
 // GetHealthCheckStatus Runs a basic healthchecks in the current node. Checks that the rabbit application
 // is running, channels and queues can be listed successfully, and that no alarms are in effect.
 func (c *Client) GetHealthCheckStatus() (rec *HealthCheckStatus, err error) {
+	c, op := c.startOperation("GetHealthCheckStatus", "", "")
+	defer op.end(&err)
+
 	req, err := newGETRequest(c, "healthchecks/node")
 	if err != nil {
 		return nil, err
@@ -47,6 +59,9 @@ func (c *Client) GetHealthCheckStatus() (rec *HealthCheckStatus, err error) {
 // GetHealthCheckStatusFor Runs a basic healthchecks in the given node. Checks that the rabbit application
 // is running, channels and queues can be listed successfully, and that no alarms are in effect.
 func (c *Client) GetHealthCheckStatusFor(name string) (rec *HealthCheckStatus, err error) {
+	c, op := c.startOperation("GetHealthCheckStatusFor", "", name)
+	defer op.end(&err)
+
 	req, err := newGETRequest(c, "healthchecks/node/"+PathEscape(name))
 	if err != nil {
 		return nil, err
@@ -58,3 +73,349 @@ func (c *Client) GetHealthCheckStatusFor(name string) (rec *HealthCheckStatus, e
 
 	return rec, nil
 }
+
+//
+// GET /api/aliveness-test/{vhost}
+//
+
+//
+// {"status":"ok"}
+//
+
+type AlivenessTestStatus struct {
+	Status string `json:"status"`
+	Reason string `json:"reason"`
+}
+
+func (ats *AlivenessTestStatus) Ok() bool {
+	return ats.Status == "ok"
+}
+
+// Aliveness declares a test queue in the given virtual host, then publishes and consumes a message.
+// Intended to be used as a basic health check for a virtual host.
+func (c *Client) Aliveness(vhost string) (rec *AlivenessTestStatus, err error) {
+	c, op := c.startOperation("Aliveness", vhost, "")
+	defer op.end(&err)
+
+	req, err := newGETRequest(c, "aliveness-test/"+PathEscape(vhost))
+	if err != nil {
+		return nil, err
+	}
+
+	if err = executeAndParseRequest(c, req, &rec); err != nil {
+		return nil, err
+	}
+
+	return rec, nil
+}
+
+// VhostAlivenessFailure describes a virtual host that did not pass the aliveness test.
+type VhostAlivenessFailure struct {
+	Vhost string
+	// Status returned by the aliveness test, nil if the request itself failed
+	Status *AlivenessTestStatus
+	// Error returned by the request, if any
+	Err error
+}
+
+// Number of aliveness tests CheckAllVhosts runs at a time. Each test
+// declares a queue and publishes to it, so the limit keeps clusters
+// with many virtual hosts from being flooded.
+const DefaultAlivenessConcurrency = 4
+
+// CheckAllVhosts runs the aliveness test against every virtual host, up to
+// DefaultAlivenessConcurrency at a time, and returns the ones that did not pass,
+// sorted by name. An error is returned only if virtual hosts cannot be listed.
+func (c *Client) CheckAllVhosts() (failures []VhostAlivenessFailure, err error) {
+	c, op := c.startOperation("CheckAllVhosts", "", "")
+	defer op.end(&err)
+
+	return c.checkAllVhosts(DefaultAlivenessConcurrency)
+}
+
+// CheckAllVhostsWithConcurrency is like CheckAllVhosts but runs up to
+// concurrency aliveness tests at a time.
+func (c *Client) CheckAllVhostsWithConcurrency(concurrency int) (failures []VhostAlivenessFailure, err error) {
+	c, op := c.startOperation("CheckAllVhostsWithConcurrency", "", "")
+	defer op.end(&err)
+
+	return c.checkAllVhosts(concurrency)
+}
+
+func (c *Client) checkAllVhosts(concurrency int) (failures []VhostAlivenessFailure, err error) {
+	if concurrency < 1 {
+		return nil, fmt.Errorf("concurrency must be positive, got %d", concurrency)
+	}
+
+	vhosts, err := c.ListVhosts()
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(chan string)
+	var (
+		wg sync.WaitGroup
+		mu sync.Mutex
+	)
+	for i := 0; i < concurrency && i < len(vhosts); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for vhost := range names {
+				st, err := c.Aliveness(vhost)
+				if err == nil && st.Ok() {
+					continue
+				}
+
+				mu.Lock()
+				failures = append(failures, VhostAlivenessFailure{Vhost: vhost, Status: st, Err: err})
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, vh := range vhosts {
+		names <- vh.Name
+	}
+	close(names)
+	wg.Wait()
+
+	sort.Slice(failures, func(i, j int) bool {
+		return failures[i].Vhost < failures[j].Vhost
+	})
+
+	return failures, nil
+}
+
+//
+// GET /api/health/checks/*
+//
+
+// Health is the common part of /api/health/checks/* responses.
+// A failed check is reported by the server with a 503 response and a
+// "failed" status, which is returned as a result rather than an error.
+type Health struct {
+	Status string `json:"status"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// Ok returns true if the check passed.
+func (h *Health) Ok() bool {
+	return h.Status == "ok"
+}
+
+// HealthCheckAlarm is a resource alarm in effect on a node.
+type HealthCheckAlarm struct {
+	Node     string `json:"node"`
+	Resource string `json:"resource"`
+}
+
+// AlarmsHealthCheckResult is returned by the alarms and local-alarms checks.
+type AlarmsHealthCheckResult struct {
+	Health
+	// Alarms in effect, if any
+	Alarms []HealthCheckAlarm `json:"alarms,omitempty"`
+}
+
+// ExpiringCertificate is a TLS certificate that expires within the checked period.
+type ExpiringCertificate struct {
+	Node       string `json:"node"`
+	Protocol   string `json:"protocol"`
+	Interface  string `json:"interface"`
+	Port       Port   `json:"port"`
+	Certfile   string `json:"certfile"`
+	CaCertfile string `json:"cacertfile"`
+	ExpiryDate string `json:"expiry_date"`
+}
+
+// CertificateExpirationHealthCheckResult is returned by the certificate-expiration check.
+type CertificateExpirationHealthCheckResult struct {
+	Health
+	// Certificates that expire within the checked period, if any
+	Expired []ExpiringCertificate `json:"expired,omitempty"`
+}
+
+// PortListenerHealthCheckResult is returned by the port-listener check.
+type PortListenerHealthCheckResult struct {
+	Health
+	// Checked port, set when the check passes
+	Port Port `json:"port,omitempty"`
+	// Checked port, set when the check fails
+	Missing Port `json:"missing,omitempty"`
+	// Ports the node actually listens on, set when the check fails
+	Ports []Port `json:"ports,omitempty"`
+}
+
+// ProtocolListenerHealthCheckResult is returned by the protocol-listener check.
+type ProtocolListenerHealthCheckResult struct {
+	Health
+	// Checked protocol, set when the check passes
+	Protocol string `json:"protocol,omitempty"`
+	// Checked protocol, set when the check fails
+	Missing string `json:"missing,omitempty"`
+	// Protocols the node actually has listeners for, set when the check fails
+	Protocols []string `json:"protocols,omitempty"`
+}
+
+// VirtualHostsHealthCheckResult is returned by the virtual-hosts check.
+type VirtualHostsHealthCheckResult struct {
+	Health
+	// Virtual hosts that are down, if any
+	VirtualHosts []string `json:"virtual-hosts,omitempty"`
+}
+
+// CriticalQueue is a queue that would lose availability (or data) if the node was shut down.
+type CriticalQueue struct {
+	Name         string `json:"name"`
+	ReadableName string `json:"readable_name"`
+	Vhost        string `json:"virtual_host"`
+	Type         string `json:"type"`
+}
+
+// QueuesHealthCheckResult is returned by the node-is-quorum-critical
+// and node-is-mirror-sync-critical checks.
+type QueuesHealthCheckResult struct {
+	Health
+	// Critical queues, if any
+	Queues []CriticalQueue `json:"queues,omitempty"`
+}
+
+// TimeUnit is a unit of time used by the certificate expiration check.
+type TimeUnit string
+
+const (
+	TimeUnitDays   TimeUnit = "days"
+	TimeUnitWeeks  TimeUnit = "weeks"
+	TimeUnitMonths TimeUnit = "months"
+	TimeUnitYears  TimeUnit = "years"
+)
+
+//
+// GET /api/health/checks/alarms
+//
+
+// CheckAlarms checks if there are resource alarms in effect in the cluster.
+func (c *Client) CheckAlarms() (rec AlarmsHealthCheckResult, err error) {
+	c, op := c.startOperation("CheckAlarms", "", "")
+	defer op.end(&err)
+
+	err = executeHealthCheck(c, "health/checks/alarms", &rec)
+	return rec, err
+}
+
+//
+// GET /api/health/checks/local-alarms
+//
+
+// CheckLocalAlarms checks if there are resource alarms in effect on the target node.
+func (c *Client) CheckLocalAlarms() (rec AlarmsHealthCheckResult, err error) {
+	c, op := c.startOperation("CheckLocalAlarms", "", "")
+	defer op.end(&err)
+
+	err = executeHealthCheck(c, "health/checks/local-alarms", &rec)
+	return rec, err
+}
+
+//
+// GET /api/health/checks/certificate-expiration/{within}/{unit}
+//
+
+// CheckCertificateExpiration checks if any TLS certificate used by listeners
+// of the target node expires within the given period, e.g. 4 TimeUnitWeeks.
+func (c *Client) CheckCertificateExpiration(within uint, unit TimeUnit) (rec CertificateExpirationHealthCheckResult, err error) {
+	c, op := c.startOperation("CheckCertificateExpiration", "", "")
+	defer op.end(&err)
+
+	err = executeHealthCheck(c, "health/checks/certificate-expiration/"+strconv.FormatUint(uint64(within), 10)+"/"+PathEscape(string(unit)), &rec)
+	return rec, err
+}
+
+//
+// GET /api/health/checks/port-listener/{port}
+//
+
+// CheckPortListener checks if there is an active listener on the given port of the target node.
+func (c *Client) CheckPortListener(port uint) (rec PortListenerHealthCheckResult, err error) {
+	c, op := c.startOperation("CheckPortListener", "", "")
+	defer op.end(&err)
+
+	err = executeHealthCheck(c, "health/checks/port-listener/"+strconv.FormatUint(uint64(port), 10), &rec)
+	return rec, err
+}
+
+//
+// GET /api/health/checks/protocol-listener/{protocol}
+//
+
+// CheckProtocolListener checks if there is an active listener for the given protocol
+// (e.g. "amqp", "amqp/ssl", "mqtt", "stomp", "http") on the target node.
+func (c *Client) CheckProtocolListener(protocol string) (rec ProtocolListenerHealthCheckResult, err error) {
+	c, op := c.startOperation("CheckProtocolListener", "", "")
+	defer op.end(&err)
+
+	err = executeHealthCheck(c, "health/checks/protocol-listener/"+PathEscape(protocol), &rec)
+	return rec, err
+}
+
+//
+// GET /api/health/checks/virtual-hosts
+//
+
+// CheckVirtualHosts checks if all virtual hosts are running on the target node.
+func (c *Client) CheckVirtualHosts() (rec VirtualHostsHealthCheckResult, err error) {
+	c, op := c.startOperation("CheckVirtualHosts", "", "")
+	defer op.end(&err)
+
+	err = executeHealthCheck(c, "health/checks/virtual-hosts", &rec)
+	return rec, err
+}
+
+//
+// GET /api/health/checks/node-is-quorum-critical
+//
+
+// CheckIfNodeIsQuorumCritical checks if there are quorum queues that would lose
+// their quorum if the target node was shut down.
+func (c *Client) CheckIfNodeIsQuorumCritical() (rec QueuesHealthCheckResult, err error) {
+	c, op := c.startOperation("CheckIfNodeIsQuorumCritical", "", "")
+	defer op.end(&err)
+
+	err = executeHealthCheck(c, "health/checks/node-is-quorum-critical", &rec)
+	return rec, err
+}
+
+//
+// GET /api/health/checks/node-is-mirror-sync-critical
+//
+
+// CheckIfNodeIsMirrorSyncCritical checks if there are classic mirrored queues
+// without synchronised mirrors online, that is, queues that would lose data
+// if the target node was shut down.
+func (c *Client) CheckIfNodeIsMirrorSyncCritical() (rec QueuesHealthCheckResult, err error) {
+	c, op := c.startOperation("CheckIfNodeIsMirrorSyncCritical", "", "")
+	defer op.end(&err)
+
+	err = executeHealthCheck(c, "health/checks/node-is-mirror-sync-critical", &rec)
+	return rec, err
+}
+
+// executeHealthCheck decodes both passed (200) and failed (503) checks into rec.
+// Any other error response, as well as transport errors, are returned as errors.
+func executeHealthCheck(client *Client, path string, rec interface{}) (err error) {
+	req, err := newGETRequest(client, path)
+	if err != nil {
+		return err
+	}
+
+	res, err := executeRequest(client, req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close() // always close body
+
+	if res.StatusCode >= http.StatusBadRequest && res.StatusCode != http.StatusServiceUnavailable {
+		return parseErrorResponse(res)
+	}
+
+	return json.NewDecoder(res.Body).Decode(rec)
+}
@@ -0,0 +1,255 @@
+/*
+Package healthz provides an http.Handler that reports RabbitMQ health,
+suitable for readiness and liveness probes (e.g. /healthz).
+
+The handler runs a configurable set of checks concurrently and responds
+with a JSON report listing every check, its outcome and how long it took.
+The response status is 200 if all checks pass and 503 otherwise.
+Checks that do not complete within the handler's Timeout, or before
+the probe's request is cancelled, are reported as failed.
+
+	rmqc, _ := rabbithole.NewClient("http://127.0.0.1:15672", "guest", "guest")
+
+	http.Handle("/healthz", healthz.NewHandler(
+		healthz.NodeHealth(rmqc, "rabbit@hostname"),
+		healthz.NodeAlarms(rmqc, "rabbit@hostname"),
+		healthz.NodePartitions(rmqc, "rabbit@hostname"),
+		healthz.VhostAliveness(rmqc, "/"),
+	))
+*/
+package healthz
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	rabbithole "github.com/michaelklishin/rabbit-hole"
+)
+
+const (
+	StatusOk     = "ok"
+	StatusFailed = "failed"
+)
+
+// DefaultTimeout is how long a Handler created with NewHandler waits for checks.
+const DefaultTimeout = 5 * time.Second
+
+// Check is a named health check. Run returns a non-nil error if the check fails.
+// It should return when ctx is done.
+type Check struct {
+	Name string
+	Run  func(ctx context.Context) error
+}
+
+// CheckResult is the outcome of an individual check.
+type CheckResult struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	// Failure reason, if any
+	Error string `json:"error,omitempty"`
+	// How long the check took, in milliseconds
+	DurationMs float64 `json:"duration_ms"`
+}
+
+// Report is the JSON document served by Handler.
+type Report struct {
+	// "ok" if all checks passed, "failed" otherwise
+	Status string `json:"status"`
+	// How long all checks took, in milliseconds
+	DurationMs float64       `json:"duration_ms"`
+	Checks     []CheckResult `json:"checks"`
+}
+
+// Handler serves a health report composed of a set of checks.
+type Handler struct {
+	// How long to wait for checks before reporting them as failed.
+	// Zero means no limit other than that of the context
+	Timeout time.Duration
+
+	checks []Check
+}
+
+// NewHandler returns a Handler running the given checks with DefaultTimeout.
+func NewHandler(checks ...Check) *Handler {
+	return &Handler{Timeout: DefaultTimeout, checks: checks}
+}
+
+// Run runs all checks concurrently and returns the report. Checks
+// still running when ctx is done or the timeout elapses are reported
+// as failed.
+func (h *Handler) Run(ctx context.Context) Report {
+	start := time.Now()
+	results := make([]CheckResult, len(h.checks))
+
+	if h.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.Timeout)
+		defer cancel()
+	}
+
+	var wg sync.WaitGroup
+	for i, check := range h.checks {
+		wg.Add(1)
+		go func(i int, check Check) {
+			defer wg.Done()
+			results[i] = runCheckWithin(ctx, check)
+		}(i, check)
+	}
+	wg.Wait()
+
+	report := Report{
+		Status:     StatusOk,
+		DurationMs: millisecondsSince(start),
+		Checks:     results,
+	}
+	for _, r := range results {
+		if r.Status != StatusOk {
+			report.Status = StatusFailed
+		}
+	}
+
+	return report
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	report := h.Run(r.Context())
+
+	code := http.StatusOK
+	if report.Status != StatusOk {
+		code = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(report)
+}
+
+// Runs the check, giving up on it when ctx is done. The check itself
+// keeps running until it returns.
+func runCheckWithin(ctx context.Context, check Check) CheckResult {
+	start := time.Now()
+	done := make(chan CheckResult, 1)
+	go func() {
+		done <- runCheck(ctx, check)
+	}()
+
+	select {
+	case res := <-done:
+		return res
+	case <-ctx.Done():
+		return CheckResult{
+			Name:       check.Name,
+			Status:     StatusFailed,
+			Error:      fmt.Sprintf("check did not complete: %v", ctx.Err()),
+			DurationMs: millisecondsSince(start),
+		}
+	}
+}
+
+func runCheck(ctx context.Context, check Check) (res CheckResult) {
+	start := time.Now()
+	res = CheckResult{Name: check.Name, Status: StatusOk}
+
+	defer func() {
+		if p := recover(); p != nil {
+			res.Status = StatusFailed
+			res.Error = fmt.Sprintf("check panicked: %v", p)
+		}
+		res.DurationMs = millisecondsSince(start)
+	}()
+
+	if err := check.Run(ctx); err != nil {
+		res.Status = StatusFailed
+		res.Error = err.Error()
+	}
+
+	return res
+}
+
+func millisecondsSince(t time.Time) float64 {
+	return float64(time.Since(t)) / float64(time.Millisecond)
+}
+
+// NodeHealth checks the node using the basic node health check
+// (see rabbithole.Client.GetHealthCheckStatusFor).
+func NodeHealth(c *rabbithole.Client, node string) Check {
+	return Check{
+		Name: "node-health:" + node,
+		Run: func(ctx context.Context) error {
+			st, err := c.WithContext(ctx).GetHealthCheckStatusFor(node)
+			if err != nil {
+				return err
+			}
+			if !st.Ok() {
+				return fmt.Errorf("node %s is not healthy: %s", node, st.Reason)
+			}
+			return nil
+		},
+	}
+}
+
+// VhostAliveness runs the aliveness test in the virtual host
+// (see rabbithole.Client.Aliveness).
+func VhostAliveness(c *rabbithole.Client, vhost string) Check {
+	return Check{
+		Name: "aliveness:" + vhost,
+		Run: func(ctx context.Context) error {
+			st, err := c.WithContext(ctx).Aliveness(vhost)
+			if err != nil {
+				return err
+			}
+			if !st.Ok() {
+				return fmt.Errorf("virtual host %s is not alive: %s", vhost, st.Reason)
+			}
+			return nil
+		},
+	}
+}
+
+// NodeAlarms fails if a memory or disk free space alarm is in effect on the node.
+func NodeAlarms(c *rabbithole.Client, node string) Check {
+	return Check{
+		Name: "alarms:" + node,
+		Run: func(ctx context.Context) error {
+			info, err := c.WithContext(ctx).GetNode(node)
+			if err != nil {
+				return err
+			}
+
+			var alarms []string
+			if info.MemAlarm {
+				alarms = append(alarms, "memory")
+			}
+			if info.DiskFreeAlarm {
+				alarms = append(alarms, "disk free space")
+			}
+			if len(alarms) > 0 {
+				return fmt.Errorf("node %s has alarms in effect: %s", node, strings.Join(alarms, ", "))
+			}
+			return nil
+		},
+	}
+}
+
+// NodePartitions fails if the node reports a network partition.
+func NodePartitions(c *rabbithole.Client, node string) Check {
+	return Check{
+		Name: "partitions:" + node,
+		Run: func(ctx context.Context) error {
+			info, err := c.WithContext(ctx).GetNode(node)
+			if err != nil {
+				return err
+			}
+			if len(info.Partitions) > 0 {
+				return fmt.Errorf("node %s is partitioned from: %s", node, strings.Join(info.Partitions, ", "))
+			}
+			return nil
+		},
+	}
+}
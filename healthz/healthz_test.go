@@ -0,0 +1,149 @@
+package healthz
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	rabbithole "github.com/michaelklishin/rabbit-hole"
+	"github.com/michaelklishin/rabbit-hole/internal/fakeapi"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// a stand-in for the RabbitMQ HTTP API serving canned responses
+func newFakeAPI(responses map[string]string) *fakeapi.Server {
+	api := fakeapi.New()
+	for path, body := range responses {
+		api.Respond(path, http.StatusOK, body)
+	}
+	return api
+}
+
+func serve(h http.Handler) (*httptest.ResponseRecorder, Report) {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/healthz", nil))
+
+	var report Report
+	Ω(json.Unmarshal(rec.Body.Bytes(), &report)).Should(Succeed())
+	return rec, report
+}
+
+var _ = Describe("Handler", func() {
+	var (
+		api  *fakeapi.Server
+		rmqc *rabbithole.Client
+	)
+
+	BeforeEach(func() {
+		api = newFakeAPI(map[string]string{
+			"/api/healthchecks/node/rabbit@healthy":   `{"status":"ok"}`,
+			"/api/healthchecks/node/rabbit@unhealthy": `{"status":"failed","reason":"boom"}`,
+			"/api/aliveness-test/%2F":                 `{"status":"ok"}`,
+			"/api/nodes/rabbit@healthy":               `{"name":"rabbit@healthy","mem_alarm":false,"disk_free_alarm":false,"partitions":[]}`,
+			"/api/nodes/rabbit@alarmed":               `{"name":"rabbit@alarmed","mem_alarm":true,"disk_free_alarm":true,"partitions":["rabbit@other"]}`,
+		})
+		rmqc, _ = rabbithole.NewClient(api.URL, "guest", "guest")
+	})
+
+	AfterEach(func() {
+		api.Close()
+	})
+
+	It("responds with 200 when all checks pass", func() {
+		rec, report := serve(NewHandler(
+			NodeHealth(rmqc, "rabbit@healthy"),
+			VhostAliveness(rmqc, "/"),
+			NodeAlarms(rmqc, "rabbit@healthy"),
+			NodePartitions(rmqc, "rabbit@healthy"),
+		))
+
+		Ω(rec.Code).Should(Equal(http.StatusOK))
+		Ω(rec.Header().Get("Content-Type")).Should(Equal("application/json"))
+		Ω(report.Status).Should(Equal(StatusOk))
+		Ω(report.Checks).Should(HaveLen(4))
+		for _, c := range report.Checks {
+			Ω(c.Status).Should(Equal(StatusOk))
+			Ω(c.Error).Should(BeEmpty())
+			Ω(c.DurationMs).Should(BeNumerically(">", 0))
+		}
+		Ω(report.Checks[0].Name).Should(Equal("node-health:rabbit@healthy"))
+		Ω(report.Checks[1].Name).Should(Equal("aliveness:/"))
+	})
+
+	It("responds with 503 when a check fails", func() {
+		rec, report := serve(NewHandler(
+			NodeHealth(rmqc, "rabbit@healthy"),
+			NodeHealth(rmqc, "rabbit@unhealthy"),
+			NodeAlarms(rmqc, "rabbit@alarmed"),
+			NodePartitions(rmqc, "rabbit@alarmed"),
+			VhostAliveness(rmqc, "missing"),
+		))
+
+		Ω(rec.Code).Should(Equal(http.StatusServiceUnavailable))
+		Ω(report.Status).Should(Equal(StatusFailed))
+		Ω(report.Checks[0].Status).Should(Equal(StatusOk))
+		Ω(report.Checks[1].Status).Should(Equal(StatusFailed))
+		Ω(report.Checks[1].Error).Should(ContainSubstring("boom"))
+		Ω(report.Checks[2].Error).Should(Equal("node rabbit@alarmed has alarms in effect: memory, disk free space"))
+		Ω(report.Checks[3].Error).Should(Equal("node rabbit@alarmed is partitioned from: rabbit@other"))
+		Ω(report.Checks[4].Error).Should(ContainSubstring("404"))
+	})
+
+	It("supports custom checks and recovers from panics", func() {
+		rec, report := serve(NewHandler(
+			Check{Name: "custom", Run: func(ctx context.Context) error { return errors.New("custom failure") }},
+			Check{Name: "panicky", Run: func(ctx context.Context) error { panic("oops") }},
+		))
+
+		Ω(rec.Code).Should(Equal(http.StatusServiceUnavailable))
+		Ω(report.Checks[0].Error).Should(Equal("custom failure"))
+		Ω(report.Checks[1].Error).Should(Equal("check panicked: oops"))
+	})
+
+	It("fails checks that do not complete in time", func() {
+		release := make(chan struct{})
+		defer close(release)
+		api.Handle("/api/nodes/rabbit@unreachable", func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-release:
+			case <-r.Context().Done():
+			}
+		})
+
+		h := NewHandler(
+			NodeHealth(rmqc, "rabbit@healthy"),
+			NodeAlarms(rmqc, "rabbit@unreachable"),
+			Check{Name: "stuck", Run: func(ctx context.Context) error {
+				<-release
+				return nil
+			}},
+		)
+		h.Timeout = 50 * time.Millisecond
+		started := time.Now()
+		rec, report := serve(h)
+
+		Ω(time.Since(started)).Should(BeNumerically("<", time.Second))
+		Ω(rec.Code).Should(Equal(http.StatusServiceUnavailable))
+		Ω(report.Checks[0].Status).Should(Equal(StatusOk))
+		Ω(report.Checks[1].Status).Should(Equal(StatusFailed))
+		Ω(report.Checks[2].Status).Should(Equal(StatusFailed))
+		Ω(report.Checks[2].Error).Should(Equal("check did not complete: context deadline exceeded"))
+	})
+
+	It("fails checks when the probe request is cancelled", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		h := NewHandler(Check{Name: "waiting", Run: func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}})
+
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", "/healthz", nil).WithContext(ctx))
+		Ω(rec.Code).Should(Equal(http.StatusServiceUnavailable))
+	})
+})
@@ -0,0 +1,108 @@
+// Package fakeapi provides a stand-in for the RabbitMQ HTTP API that
+// serves canned responses and records the requests it receives.
+// It is only meant to be used in tests.
+package fakeapi
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+)
+
+// Request is a request received by a Server.
+type Request struct {
+	Method string
+	// Path as sent, i.e. with percent-encoding preserved
+	Path   string
+	Header http.Header
+	Body   string
+	// Basic authentication credentials, if any
+	Username string
+	Password string
+}
+
+// Server is a fake RabbitMQ HTTP API. Paths without a handler
+// respond with 404 Not Found, like the real API does.
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	requests []Request
+	handlers map[string]http.HandlerFunc
+	fallback http.HandlerFunc
+}
+
+// New starts a Server. Stop it with Close.
+func New() *Server {
+	s := &Server{
+		handlers: make(map[string]http.HandlerFunc),
+		fallback: JSON(http.StatusNotFound, `{"error":"Object Not Found","reason":"Not Found"}`),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+// Handle sets the handler of requests to path, e.g. /api/vhosts.
+func (s *Server) Handle(path string, h http.HandlerFunc) *Server {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.handlers[path] = h
+	return s
+}
+
+// Respond makes requests to path respond with status and body.
+func (s *Server) Respond(path string, status int, body string) *Server {
+	return s.Handle(path, JSON(status, body))
+}
+
+// HandleOthers sets the handler of requests to paths without a handler.
+func (s *Server) HandleOthers(h http.HandlerFunc) *Server {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.fallback = h
+	return s
+}
+
+// Requests returns the requests received so far, in the order they were received.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Request{}, s.requests...)
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	username, password, _ := r.BasicAuth()
+
+	s.mu.Lock()
+	s.requests = append(s.requests, Request{
+		Method:   r.Method,
+		Path:     r.URL.EscapedPath(),
+		Header:   r.Header,
+		Body:     string(body),
+		Username: username,
+		Password: password,
+	})
+	h, ok := s.handlers[r.URL.EscapedPath()]
+	if !ok {
+		h = s.fallback
+	}
+	s.mu.Unlock()
+
+	h(w, r)
+}
+
+// JSON returns a handler that responds with status and body.
+func JSON(status int, body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}
+}
@@ -0,0 +1,104 @@
+package rabbithole
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Logger records client operations. It is satisfied by *slog.Logger,
+// so a structured logger can be used directly:
+//
+//	rmqc.SetLogger(slog.Default())
+//
+// Messages are followed by alternating attribute keys and values.
+type Logger interface {
+	Info(msg string, args ...interface{})
+	Error(msg string, args ...interface{})
+}
+
+// Replaces credentials in logged values
+const redacted = "[REDACTED]"
+
+// Matches password and password hash values in JSON documents
+var passwordFieldPattern = regexp.MustCompile(`"(password|password_hash|client_secret)"\s*:\s*"(?:[^"\\]|\\.)*"`)
+
+// SetLogger makes the Client log the method, path, status and duration
+// of every request it sends, as well as the error RabbitMQ responded with,
+// if any. Credentials are never logged. Pass nil to disable logging.
+func (c *Client) SetLogger(l Logger) {
+	c.logger = l
+}
+
+func loggingMiddleware(l Logger) Middleware {
+	return func(next RequestHandler) RequestHandler {
+		return func(req *http.Request) (*http.Response, error) {
+			started := time.Now()
+			res, err := next(req)
+
+			args := []interface{}{
+				"method", req.Method,
+				"path", requestPath(req),
+				"duration", time.Since(started),
+			}
+			if err != nil {
+				l.Error("rabbithole request failed", append(args, "error", redact(req, err.Error()))...)
+				return res, err
+			}
+
+			args = append(args, "status", res.StatusCode)
+			if res.StatusCode < http.StatusBadRequest {
+				l.Info("rabbithole request", args...)
+				return res, nil
+			}
+
+			rme, body := peekErrorResponse(res)
+			res.Body = ioutil.NopCloser(bytes.NewReader(body))
+			args = append(args, "error", redact(req, rme.Message), "reason", redact(req, rme.Reason))
+			l.Error("rabbithole request failed", args...)
+
+			return res, nil
+		}
+	}
+}
+
+// Decodes an error response, leaving its body readable.
+func peekErrorResponse(res *http.Response) (ErrorResponse, []byte) {
+	rme := ErrorResponse{StatusCode: res.StatusCode}
+
+	body, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return rme, body
+	}
+	json.Unmarshal(body, &rme)
+
+	return rme, body
+}
+
+// Path of the request as sent, i.e. with percent-encoding preserved.
+func requestPath(req *http.Request) string {
+	if req.URL.Opaque != "" {
+		return strings.TrimPrefix(req.URL.Opaque, "//"+req.URL.Host)
+	}
+	return req.URL.EscapedPath()
+}
+
+// Removes credentials used by the request, and passwords in JSON
+// documents (e.g. UserSettings), from s.
+func redact(req *http.Request, s string) string {
+	s = passwordFieldPattern.ReplaceAllString(s, `"$1":"`+redacted+`"`)
+
+	if _, password, ok := req.BasicAuth(); ok && password != "" {
+		s = strings.Replace(s, password, redacted, -1)
+	}
+	if auth := req.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") && len(auth) > len("Bearer ") {
+		s = strings.Replace(s, strings.TrimPrefix(auth, "Bearer "), redacted, -1)
+	}
+
+	return s
+}
@@ -0,0 +1,80 @@
+package rabbithole
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+// RequestHandler sends a request to RabbitMQ and returns its response.
+type RequestHandler func(req *http.Request) (*http.Response, error)
+
+// Middleware wraps a RequestHandler, e.g. to add headers, log, measure latency
+// or enforce policies. A middleware can also respond without calling next:
+//
+//	func Timing(next RequestHandler) RequestHandler {
+//		return func(req *http.Request) (*http.Response, error) {
+//			started := time.Now()
+//			res, err := next(req)
+//			log.Printf("%s %s took %s", req.Method, req.URL.Path, time.Since(started))
+//			return res, err
+//		}
+//	}
+type Middleware func(next RequestHandler) RequestHandler
+
+// Use adds middlewares to the chain every request passes through.
+// Middlewares run in the order they were added, after the request
+// is authenticated and before it is sent.
+func (c *Client) Use(middlewares ...Middleware) {
+	c.middlewares = append(c.middlewares, middlewares...)
+}
+
+// Header used by RequestIDMiddleware unless another one is specified
+const DefaultRequestIDHeader = "X-Request-ID"
+
+// RequestIDMiddleware sets a random request ID header on requests that do not
+// have one already, so that they can be correlated with server-side logs.
+// Uses DefaultRequestIDHeader if header is empty.
+func RequestIDMiddleware(header string) Middleware {
+	if header == "" {
+		header = DefaultRequestIDHeader
+	}
+
+	return func(next RequestHandler) RequestHandler {
+		return func(req *http.Request) (*http.Response, error) {
+			if req.Header.Get(header) == "" {
+				id, err := newRequestID()
+				if err != nil {
+					return nil, err
+				}
+				req.Header.Set(header, id)
+			}
+			return next(req)
+		}
+	}
+}
+
+func newRequestID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// StaticHeadersMiddleware sets the given headers on every request.
+func StaticHeadersMiddleware(headers map[string]string) Middleware {
+	hs := make(http.Header, len(headers))
+	for k, v := range headers {
+		hs.Set(k, v)
+	}
+
+	return func(next RequestHandler) RequestHandler {
+		return func(req *http.Request) (*http.Response, error) {
+			for k, v := range hs {
+				req.Header[k] = v
+			}
+			return next(req)
+		}
+	}
+}
@@ -47,6 +47,13 @@ type Overview struct {
 }
 
 func (c *Client) Overview() (rec *Overview, err error) {
+	c, op := c.startOperation("Overview", "", "")
+	defer op.end(&err)
+
+	return c.overview()
+}
+
+func (c *Client) overview() (rec *Overview, err error) {
 	req, err := newGETRequest(c, "overview")
 	if err != nil {
 		return nil, err
@@ -70,6 +77,9 @@ type WhoamiInfo struct {
 }
 
 func (c *Client) Whoami() (rec *WhoamiInfo, err error) {
+	c, op := c.startOperation("Whoami", "", "")
+	defer op.end(&err)
+
 	req, err := newGETRequest(c, "whoami")
 	if err != nil {
 		return nil, err
@@ -57,6 +57,9 @@ type NodeInfo struct {
 //
 
 func (c *Client) ListNodes() (rec []NodeInfo, err error) {
+	c, op := c.startOperation("ListNodes", "", "")
+	defer op.end(&err)
+
 	req, err := newGETRequest(c, "nodes")
 	if err != nil {
 		return []NodeInfo{}, err
@@ -287,6 +290,9 @@ func (c *Client) ListNodes() (rec []NodeInfo, err error) {
 // }
 
 func (c *Client) GetNode(name string) (rec *NodeInfo, err error) {
+	c, op := c.startOperation("GetNode", "", name)
+	defer op.end(&err)
+
 	req, err := newGETRequest(c, "nodes/"+PathEscape(name))
 	if err != nil {
 		return nil, err
@@ -0,0 +1,66 @@
+package rabbithole
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"fmt"
+	"hash"
+)
+
+// Password hashing algorithm used by the internal authentication backend.
+type HashingAlgorithm string
+
+const (
+	HashingAlgorithmSHA256 HashingAlgorithm = "rabbit_password_hashing_sha256"
+	HashingAlgorithmSHA512 HashingAlgorithm = "rabbit_password_hashing_sha512"
+	// Only use MD5 for compatibility with RabbitMQ versions prior to 3.6.0
+	HashingAlgorithmMD5 HashingAlgorithm = "rabbit_password_hashing_md5"
+)
+
+// Length of salts generated by RabbitMQ, in bytes
+const passwordSaltLength = 4
+
+func (a HashingAlgorithm) newHash() (hash.Hash, error) {
+	switch a {
+	case HashingAlgorithmSHA256:
+		return sha256.New(), nil
+	case HashingAlgorithmSHA512:
+		return sha512.New(), nil
+	case HashingAlgorithmMD5:
+		return md5.New(), nil
+	default:
+		return nil, fmt.Errorf("unsupported hashing algorithm %q", a)
+	}
+}
+
+// HashPassword computes a password hash the way RabbitMQ does, using a random
+// salt. The result can be used as UserSettings.PasswordHash, so that
+// the password itself is never sent to the HTTP API:
+//
+//	h, err := HashPassword("s3krE7", HashingAlgorithmSHA256)
+//	resp, err := rmqc.PutUser("my.user", UserSettings{PasswordHash: h, HashingAlgorithm: HashingAlgorithmSHA256})
+func HashPassword(password string, algorithm HashingAlgorithm) (string, error) {
+	salt := make([]byte, passwordSaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	return HashPasswordWithSalt(password, salt, algorithm)
+}
+
+// HashPasswordWithSalt computes a password hash with the given salt: the
+// base64-encoded salt followed by the hash of the salt and the password.
+func HashPasswordWithSalt(password string, salt []byte, algorithm HashingAlgorithm) (string, error) {
+	h, err := algorithm.newHash()
+	if err != nil {
+		return "", err
+	}
+
+	h.Write(salt)
+	h.Write([]byte(password))
+
+	return base64.StdEncoding.EncodeToString(h.Sum(append([]byte{}, salt...))), nil
+}
@@ -0,0 +1,324 @@
+package rabbithole
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// AMQP operation subject to permission checks.
+type AMQPOperation string
+
+const (
+	OpDeclareQueue    AMQPOperation = "declare-queue"
+	OpDeleteQueue     AMQPOperation = "delete-queue"
+	OpPurgeQueue      AMQPOperation = "purge-queue"
+	OpDeclareExchange AMQPOperation = "declare-exchange"
+	OpDeleteExchange  AMQPOperation = "delete-exchange"
+	// Bind (or unbind) a queue to an exchange
+	OpBindQueue AMQPOperation = "bind-queue"
+	// Bind (or unbind) an exchange to another exchange
+	OpBindExchange AMQPOperation = "bind-exchange"
+	OpPublish      AMQPOperation = "publish"
+	// Consume from, or get messages from, a queue
+	OpConsume AMQPOperation = "consume"
+)
+
+// Kind of resource permissions are checked on.
+type PermissionResourceKind string
+
+const (
+	PermissionResourceQueue    PermissionResourceKind = "queue"
+	PermissionResourceExchange PermissionResourceKind = "exchange"
+	// Routing or binding key of a topic exchange
+	PermissionResourceTopic PermissionResourceKind = "topic"
+)
+
+// The default exchange is named amq.default in permission checks.
+const defaultExchangePermissionName = "amq.default"
+
+// Stands for the name of a server-named queue, which is only known once declared.
+const serverNamedQueuePermissionName = "amq.gen-AAAAAAAAAAAAAAAAAAAAAA"
+
+// PermissionRequest describes an AMQP operation to evaluate.
+type PermissionRequest struct {
+	Vhost     string
+	Operation AMQPOperation
+	// Queue declared, deleted, purged, bound or consumed from.
+	// Empty declares a server-named queue
+	Queue string
+	// Exchange declared, deleted or published to, or source of a binding.
+	// Empty is the default exchange
+	Exchange string
+	// Destination of an exchange-to-exchange binding
+	DestinationExchange string
+	// Type of Exchange. Topic permissions are only checked for "topic"
+	ExchangeType string
+	// Routing key of published messages, or binding key
+	RoutingKey string
+}
+
+// PermissionCheck is an individual check performed for a PermissionRequest.
+type PermissionCheck struct {
+	// "configure", "write" or "read"
+	Permission string
+	Kind       PermissionResourceKind
+	// Resource name: queue or exchange name, or routing key for topic checks
+	Name string
+	// Pattern the name was matched against, empty if there is none
+	Pattern string
+	Allowed bool
+}
+
+// PermissionDecision is the outcome of evaluating a PermissionRequest.
+type PermissionDecision struct {
+	Allowed bool
+	// Why the operation is refused regardless of permissions, if it is
+	Reason string
+	Checks []PermissionCheck
+}
+
+// ResourceAccess lists the permissions a user has on a resource.
+type ResourceAccess struct {
+	Vhost     string
+	Kind      PermissionResourceKind
+	Name      string
+	Configure bool
+	Write     bool
+	Read      bool
+}
+
+// PermissionEvaluator answers questions such as "can the user publish to this
+// exchange?" by applying the user's permissions the way RabbitMQ does:
+//
+//	perms, _ := rmqc.ListPermissionsOf("my.user")
+//	topicPerms, _ := rmqc.ListTopicPermissionsOf("my.user")
+//	e, err := NewPermissionEvaluator("my.user", perms, topicPerms)
+//	d, err := e.Evaluate(PermissionRequest{Vhost: "/", Operation: OpPublish, Exchange: "an.exchange"})
+//
+// Patterns are evaluated like those of policies, see EvaluatePolicies.
+type PermissionEvaluator struct {
+	user        string
+	permissions map[string]compiledPermissions
+	topics      map[string]map[string]compiledTopicPermissions
+}
+
+type compiledPermissions struct {
+	info                   PermissionInfo
+	configure, write, read *regexp.Regexp
+}
+
+type compiledTopicPermissions struct {
+	info        TopicPermissionInfo
+	write, read *regexp.Regexp
+}
+
+// NewPermissionEvaluator returns an evaluator for the user. Permissions and topic
+// permissions of other users are ignored.
+func NewPermissionEvaluator(user string, permissions []PermissionInfo, topicPermissions []TopicPermissionInfo) (*PermissionEvaluator, error) {
+	e := &PermissionEvaluator{
+		user:        user,
+		permissions: make(map[string]compiledPermissions),
+		topics:      make(map[string]map[string]compiledTopicPermissions),
+	}
+
+	for _, p := range permissions {
+		if p.User != user {
+			continue
+		}
+		cp := compiledPermissions{info: p}
+		var err error
+		if cp.configure, err = compilePermissionPattern(p.Configure, "configure", p.Vhost); err != nil {
+			return nil, err
+		}
+		if cp.write, err = compilePermissionPattern(p.Write, "write", p.Vhost); err != nil {
+			return nil, err
+		}
+		if cp.read, err = compilePermissionPattern(p.Read, "read", p.Vhost); err != nil {
+			return nil, err
+		}
+		e.permissions[p.Vhost] = cp
+	}
+
+	for _, p := range topicPermissions {
+		if p.User != user {
+			continue
+		}
+		// topic permission patterns can refer to the user and virtual host
+		expand := strings.NewReplacer("{username}", user, "{vhost}", p.Vhost)
+		cp := compiledTopicPermissions{info: p}
+		var err error
+		if cp.write, err = compilePermissionPattern(expand.Replace(p.Write), "topic write", p.Vhost); err != nil {
+			return nil, err
+		}
+		if cp.read, err = compilePermissionPattern(expand.Replace(p.Read), "topic read", p.Vhost); err != nil {
+			return nil, err
+		}
+		if e.topics[p.Vhost] == nil {
+			e.topics[p.Vhost] = make(map[string]compiledTopicPermissions)
+		}
+		e.topics[p.Vhost][p.Exchange] = cp
+	}
+
+	return e, nil
+}
+
+// An empty pattern grants no access.
+func compilePermissionPattern(pattern, permission, vhost string) (*regexp.Regexp, error) {
+	if pattern == "" {
+		pattern = "^$"
+	}
+	re, err := compileBrokerPattern(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s permission pattern %q in vhost %s: %s", permission, pattern, vhost, err)
+	}
+	return re, nil
+}
+
+func exchangePermissionName(name string) string {
+	if name == "" {
+		return defaultExchangePermissionName
+	}
+	return name
+}
+
+// Evaluate returns whether the user is allowed to perform the operation,
+// along with every check it involves.
+func (e *PermissionEvaluator) Evaluate(req PermissionRequest) (PermissionDecision, error) {
+	d := PermissionDecision{}
+	exchange := exchangePermissionName(req.Exchange)
+
+	switch req.Operation {
+	case OpDeclareQueue:
+		queue := req.Queue
+		if queue == "" {
+			queue = serverNamedQueuePermissionName
+		} else if strings.HasPrefix(queue, "amq.") {
+			d.Reason = fmt.Sprintf("queue name %q uses the reserved amq. prefix", queue)
+		}
+		e.check(&d, req.Vhost, "configure", PermissionResourceQueue, queue)
+	case OpDeleteQueue, OpPurgeQueue, OpConsume:
+		if req.Queue == "" {
+			return d, errors.New("a queue name is required")
+		}
+		permission := "configure"
+		if req.Operation != OpDeleteQueue {
+			permission = "read"
+		}
+		e.check(&d, req.Vhost, permission, PermissionResourceQueue, req.Queue)
+	case OpDeclareExchange, OpDeleteExchange:
+		if req.Exchange == "" || strings.HasPrefix(req.Exchange, "amq.") {
+			d.Reason = fmt.Sprintf("exchange %q is predeclared or uses the reserved amq. prefix", exchange)
+		}
+		e.check(&d, req.Vhost, "configure", PermissionResourceExchange, exchange)
+	case OpBindQueue:
+		if req.Queue == "" {
+			return d, errors.New("a queue name is required")
+		}
+		if req.Exchange == "" {
+			d.Reason = "queues cannot be bound to the default exchange"
+		}
+		e.check(&d, req.Vhost, "write", PermissionResourceQueue, req.Queue)
+		e.check(&d, req.Vhost, "read", PermissionResourceExchange, exchange)
+		e.checkTopic(&d, req, "read")
+	case OpBindExchange:
+		if req.DestinationExchange == "" || req.Exchange == "" {
+			d.Reason = "the default exchange cannot be bound"
+		}
+		e.check(&d, req.Vhost, "write", PermissionResourceExchange, exchangePermissionName(req.DestinationExchange))
+		e.check(&d, req.Vhost, "read", PermissionResourceExchange, exchange)
+		e.checkTopic(&d, req, "read")
+	case OpPublish:
+		e.check(&d, req.Vhost, "write", PermissionResourceExchange, exchange)
+		e.checkTopic(&d, req, "write")
+	default:
+		return d, fmt.Errorf("unknown operation %q", req.Operation)
+	}
+
+	d.Allowed = d.Reason == ""
+	for _, c := range d.Checks {
+		d.Allowed = d.Allowed && c.Allowed
+	}
+	return d, nil
+}
+
+func (e *PermissionEvaluator) check(d *PermissionDecision, vhost, permission string, kind PermissionResourceKind, name string) {
+	c := PermissionCheck{Permission: permission, Kind: kind, Name: name}
+
+	if p, ok := e.permissions[vhost]; ok {
+		switch permission {
+		case "configure":
+			c.Pattern, c.Allowed = p.info.Configure, p.configure.MatchString(name)
+		case "write":
+			c.Pattern, c.Allowed = p.info.Write, p.write.MatchString(name)
+		case "read":
+			c.Pattern, c.Allowed = p.info.Read, p.read.MatchString(name)
+		}
+	}
+
+	d.Checks = append(d.Checks, c)
+}
+
+// Topic permissions only restrict topic exchanges they are defined for.
+func (e *PermissionEvaluator) checkTopic(d *PermissionDecision, req PermissionRequest, permission string) {
+	if req.ExchangeType != "topic" {
+		return
+	}
+	p, ok := e.topics[req.Vhost][req.Exchange]
+	if !ok {
+		return
+	}
+
+	c := PermissionCheck{Permission: permission, Kind: PermissionResourceTopic, Name: req.RoutingKey}
+	if permission == "write" {
+		c.Pattern, c.Allowed = p.info.Write, p.write.MatchString(req.RoutingKey)
+	} else {
+		c.Pattern, c.Allowed = p.info.Read, p.read.MatchString(req.RoutingKey)
+	}
+	d.Checks = append(d.Checks, c)
+}
+
+// Report lists the queues and exchanges (e.g. from ListQueues and ListExchanges)
+// the user has at least one permission on, ordered by virtual host, kind and name.
+func (e *PermissionEvaluator) Report(queues []QueueInfo, exchanges []ExchangeInfo) []ResourceAccess {
+	var xs []ResourceAccess
+
+	add := func(vhost string, kind PermissionResourceKind, name string) {
+		p, ok := e.permissions[vhost]
+		if !ok {
+			return
+		}
+		ra := ResourceAccess{
+			Vhost:     vhost,
+			Kind:      kind,
+			Name:      name,
+			Configure: p.configure.MatchString(name),
+			Write:     p.write.MatchString(name),
+			Read:      p.read.MatchString(name),
+		}
+		if ra.Configure || ra.Write || ra.Read {
+			xs = append(xs, ra)
+		}
+	}
+
+	for _, q := range queues {
+		add(q.Vhost, PermissionResourceQueue, q.Name)
+	}
+	for _, x := range exchanges {
+		add(x.Vhost, PermissionResourceExchange, exchangePermissionName(x.Name))
+	}
+
+	sort.Slice(xs, func(i, j int) bool {
+		if xs[i].Vhost != xs[j].Vhost {
+			return xs[i].Vhost < xs[j].Vhost
+		}
+		if xs[i].Kind != xs[j].Kind {
+			return xs[i].Kind < xs[j].Kind
+		}
+		return xs[i].Name < xs[j].Name
+	})
+
+	return xs
+}
@@ -27,6 +27,9 @@ type PermissionInfo struct {
 
 // Returns permissions for all users and virtual hosts.
 func (c *Client) ListPermissions() (rec []PermissionInfo, err error) {
+	c, op := c.startOperation("ListPermissions", "", "")
+	defer op.end(&err)
+
 	req, err := newGETRequest(c, "permissions/")
 	if err != nil {
 		return []PermissionInfo{}, err
@@ -45,6 +48,9 @@ func (c *Client) ListPermissions() (rec []PermissionInfo, err error) {
 
 // Returns permissions of a specific user.
 func (c *Client) ListPermissionsOf(username string) (rec []PermissionInfo, err error) {
+	c, op := c.startOperation("ListPermissionsOf", "", username)
+	defer op.end(&err)
+
 	req, err := newGETRequest(c, "users/"+PathEscape(username)+"/permissions")
 	if err != nil {
 		return []PermissionInfo{}, err
@@ -63,6 +69,9 @@ func (c *Client) ListPermissionsOf(username string) (rec []PermissionInfo, err e
 
 // Returns permissions of user in virtual host.
 func (c *Client) GetPermissionsIn(vhost, username string) (rec PermissionInfo, err error) {
+	c, op := c.startOperation("GetPermissionsIn", vhost, username)
+	defer op.end(&err)
+
 	req, err := newGETRequest(c, "permissions/"+PathEscape(vhost)+"/"+PathEscape(username))
 	if err != nil {
 		return PermissionInfo{}, err
@@ -87,6 +96,9 @@ type Permissions struct {
 
 // Updates permissions of user in virtual host.
 func (c *Client) UpdatePermissionsIn(vhost, username string, permissions Permissions) (res *http.Response, err error) {
+	c, op := c.startOperation("UpdatePermissionsIn", vhost, username)
+	defer op.end(&err)
+
 	body, err := json.Marshal(permissions)
 	if err != nil {
 		return nil, err
@@ -111,6 +123,9 @@ func (c *Client) UpdatePermissionsIn(vhost, username string, permissions Permiss
 
 // Clears (deletes) permissions of user in virtual host.
 func (c *Client) ClearPermissionsIn(vhost, username string) (res *http.Response, err error) {
+	c, op := c.startOperation("ClearPermissionsIn", vhost, username)
+	defer op.end(&err)
+
 	req, err := newRequestWithBody(c, "DELETE", "permissions/"+PathEscape(vhost)+"/"+PathEscape(username), nil)
 	if err != nil {
 		return nil, err
@@ -123,3 +138,61 @@ func (c *Client) ClearPermissionsIn(vhost, username string) (res *http.Response,
 
 	return res, nil
 }
+
+//
+// GET /api/topic-permissions
+//
+
+// Example response:
+//
+// [{"user":"guest","vhost":"/","exchange":"amq.topic","write":"^a","read":".*"}]
+
+type TopicPermissionInfo struct {
+	User  string `json:"user"`
+	Vhost string `json:"vhost"`
+
+	// Topic exchange these permissions apply to
+	Exchange string `json:"exchange"`
+	// Write permissions, matched against routing keys of published messages
+	Write string `json:"write"`
+	// Read permissions, matched against binding keys
+	Read string `json:"read"`
+}
+
+// Returns topic permissions for all users and virtual hosts.
+func (c *Client) ListTopicPermissions() (rec []TopicPermissionInfo, err error) {
+	c, op := c.startOperation("ListTopicPermissions", "", "")
+	defer op.end(&err)
+
+	req, err := newGETRequest(c, "topic-permissions/")
+	if err != nil {
+		return []TopicPermissionInfo{}, err
+	}
+
+	if err = executeAndParseRequest(c, req, &rec); err != nil {
+		return []TopicPermissionInfo{}, err
+	}
+
+	return rec, nil
+}
+
+//
+// GET /api/users/{user}/topic-permissions
+//
+
+// Returns topic permissions of a specific user.
+func (c *Client) ListTopicPermissionsOf(username string) (rec []TopicPermissionInfo, err error) {
+	c, op := c.startOperation("ListTopicPermissionsOf", "", username)
+	defer op.end(&err)
+
+	req, err := newGETRequest(c, "users/"+PathEscape(username)+"/topic-permissions")
+	if err != nil {
+		return []TopicPermissionInfo{}, err
+	}
+
+	if err = executeAndParseRequest(c, req, &rec); err != nil {
+		return []TopicPermissionInfo{}, err
+	}
+
+	return rec, nil
+}
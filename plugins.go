@@ -1,7 +1,10 @@
 package rabbithole
 
 func (c *Client) EnabledProtocols() (xs []string, err error) {
-	overview, err := c.Overview()
+	c, op := c.startOperation("EnabledProtocols", "", "")
+	defer op.end(&err)
+
+	overview, err := c.overview()
 	if err != nil {
 		return []string{}, err
 	}
@@ -16,9 +19,12 @@ func (c *Client) EnabledProtocols() (xs []string, err error) {
 }
 
 func (c *Client) ProtocolPorts() (res map[string]Port, err error) {
+	c, op := c.startOperation("ProtocolPorts", "", "")
+	defer op.end(&err)
+
 	res = map[string]Port{}
 
-	overview, err := c.Overview()
+	overview, err := c.overview()
 	if err != nil {
 		return res, err
 	}
@@ -10,6 +10,7 @@ import (
 // that match a policy.
 type PolicyDefinition map[string]interface{}
 
+// List of node names, e.g. ha-params of the "nodes" ha-mode.
 type NodeNames []string
 
 // Represents a configured policy.
@@ -34,6 +35,9 @@ type Policy struct {
 
 // Return all policies (across all virtual hosts).
 func (c *Client) ListPolicies() (rec []Policy, err error) {
+	c, op := c.startOperation("ListPolicies", "", "")
+	defer op.end(&err)
+
 	req, err := newGETRequest(c, "policies")
 	if err != nil {
 		return nil, err
@@ -52,6 +56,9 @@ func (c *Client) ListPolicies() (rec []Policy, err error) {
 
 // Returns policies in a specific virtual host.
 func (c *Client) ListPoliciesIn(vhost string) (rec []Policy, err error) {
+	c, op := c.startOperation("ListPoliciesIn", vhost, "")
+	defer op.end(&err)
+
 	req, err := newGETRequest(c, "policies/"+PathEscape(vhost))
 	if err != nil {
 		return nil, err
@@ -70,6 +77,9 @@ func (c *Client) ListPoliciesIn(vhost string) (rec []Policy, err error) {
 
 // Returns individual policy in virtual host.
 func (c *Client) GetPolicy(vhost, name string) (rec *Policy, err error) {
+	c, op := c.startOperation("GetPolicy", vhost, name)
+	defer op.end(&err)
+
 	req, err := newGETRequest(c, "policies/"+PathEscape(vhost)+"/"+PathEscape(name))
 	if err != nil {
 		return nil, err
@@ -86,8 +96,15 @@ func (c *Client) GetPolicy(vhost, name string) (rec *Policy, err error) {
 // PUT /api/policies/{vhost}/{name}
 //
 
-// Updates a policy.
+// Updates a policy. The definition is validated first (see PolicyDefinition.Validate).
 func (c *Client) PutPolicy(vhost string, name string, policy Policy) (res *http.Response, err error) {
+	c, op := c.startOperation("PutPolicy", vhost, name)
+	defer op.end(&err)
+
+	if err = policy.Definition.Validate(); err != nil {
+		return nil, err
+	}
+
 	body, err := json.Marshal(policy)
 	if err != nil {
 		return nil, err
@@ -112,6 +129,9 @@ func (c *Client) PutPolicy(vhost string, name string, policy Policy) (res *http.
 
 // Deletes a policy.
 func (c *Client) DeletePolicy(vhost, name string) (res *http.Response, err error) {
+	c, op := c.startOperation("DeletePolicy", vhost, name)
+	defer op.end(&err)
+
 	req, err := newRequestWithBody(c, "DELETE", "policies/"+PathEscape(vhost)+"/"+PathEscape(name), nil)
 	if err != nil {
 		return nil, err
@@ -0,0 +1,282 @@
+package rabbithole
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Classic queue mirroring mode (ha-mode).
+type HaMode string
+
+const (
+	// Mirror to all nodes
+	HaModeAll HaMode = "all"
+	// Mirror to a number of nodes, set by ha-params
+	HaModeExactly HaMode = "exactly"
+	// Mirror to specific nodes, set by ha-params
+	HaModeNodes HaMode = "nodes"
+)
+
+// Classic queue mirror synchronisation mode (ha-sync-mode).
+type HaSyncMode string
+
+const (
+	HaSyncModeManual    HaSyncMode = "manual"
+	HaSyncModeAutomatic HaSyncMode = "automatic"
+)
+
+// Strategy used to pick the node hosting a queue master (queue-master-locator).
+type QueueMasterLocator string
+
+const (
+	QueueMasterLocatorMinMasters  QueueMasterLocator = "min-masters"
+	QueueMasterLocatorClientLocal QueueMasterLocator = "client-local"
+	QueueMasterLocatorRandom      QueueMasterLocator = "random"
+)
+
+// PolicyDefinitionBuilder builds policy definitions from well-known policy keys
+// with the types RabbitMQ expects. Invalid values and combinations are reported by Build:
+//
+//	def, err := NewPolicyDefinition().
+//		HaMode(HaModeExactly).
+//		HaParamsCount(2).
+//		HaSyncMode(HaSyncModeAutomatic).
+//		Build()
+//
+//	resp, err := rmqc.PutPolicy("/", "ha", Policy{Pattern: "^ha\\.", ApplyTo: "queues", Definition: def})
+type PolicyDefinitionBuilder struct {
+	def PolicyDefinition
+	err error
+}
+
+// NewPolicyDefinition returns an empty PolicyDefinitionBuilder.
+func NewPolicyDefinition() *PolicyDefinitionBuilder {
+	return &PolicyDefinitionBuilder{def: PolicyDefinition{}}
+}
+
+func (b *PolicyDefinitionBuilder) set(key string, value interface{}) *PolicyDefinitionBuilder {
+	b.def[key] = value
+	return b
+}
+
+func (b *PolicyDefinitionBuilder) fail(format string, a ...interface{}) *PolicyDefinitionBuilder {
+	if b.err == nil {
+		b.err = fmt.Errorf(format, a...)
+	}
+	return b
+}
+
+// HaMode sets the classic queue mirroring mode (ha-mode).
+func (b *PolicyDefinitionBuilder) HaMode(mode HaMode) *PolicyDefinitionBuilder {
+	return b.set("ha-mode", string(mode))
+}
+
+// HaParamsCount sets the number of mirrors for the "exactly" mode (ha-params).
+func (b *PolicyDefinitionBuilder) HaParamsCount(n int) *PolicyDefinitionBuilder {
+	return b.set("ha-params", n)
+}
+
+// HaParamsNodes sets the nodes to mirror to for the "nodes" mode (ha-params).
+func (b *PolicyDefinitionBuilder) HaParamsNodes(nodes NodeNames) *PolicyDefinitionBuilder {
+	return b.set("ha-params", nodes)
+}
+
+// HaSyncMode sets the mirror synchronisation mode (ha-sync-mode).
+func (b *PolicyDefinitionBuilder) HaSyncMode(mode HaSyncMode) *PolicyDefinitionBuilder {
+	return b.set("ha-sync-mode", string(mode))
+}
+
+// FederationUpstream federates matching entities from an upstream (federation-upstream).
+func (b *PolicyDefinitionBuilder) FederationUpstream(upstream string) *PolicyDefinitionBuilder {
+	return b.set("federation-upstream", upstream)
+}
+
+// FederationUpstreamSet federates matching entities from an upstream set,
+// or from all upstreams with "all" (federation-upstream-set).
+func (b *PolicyDefinitionBuilder) FederationUpstreamSet(set string) *PolicyDefinitionBuilder {
+	return b.set("federation-upstream-set", set)
+}
+
+// MessageTTL sets how long messages can stay in matching queues (message-ttl).
+// The TTL is sent in milliseconds.
+func (b *PolicyDefinitionBuilder) MessageTTL(ttl time.Duration) *PolicyDefinitionBuilder {
+	if ttl < 0 {
+		return b.fail("message-ttl must not be negative, got %s", ttl)
+	}
+	return b.set("message-ttl", int64(ttl/time.Millisecond))
+}
+
+// Expires sets how long matching queues can stay unused before they are deleted (expires).
+// The value is sent in milliseconds.
+func (b *PolicyDefinitionBuilder) Expires(after time.Duration) *PolicyDefinitionBuilder {
+	if after < time.Millisecond {
+		return b.fail("expires must be at least 1ms, got %s", after)
+	}
+	return b.set("expires", int64(after/time.Millisecond))
+}
+
+// MaxLength sets the maximum number of ready messages in matching queues (max-length).
+func (b *PolicyDefinitionBuilder) MaxLength(n int64) *PolicyDefinitionBuilder {
+	return b.set("max-length", n)
+}
+
+// Overflow sets what happens when matching queues reach their max length (overflow).
+func (b *PolicyDefinitionBuilder) Overflow(o QueueOverflow) *PolicyDefinitionBuilder {
+	return b.set("overflow", string(o))
+}
+
+// DeadLetterExchange sets the exchange dead-lettered messages are republished to
+// (dead-letter-exchange).
+func (b *PolicyDefinitionBuilder) DeadLetterExchange(exchange string) *PolicyDefinitionBuilder {
+	return b.set("dead-letter-exchange", exchange)
+}
+
+// QueueMode sets the classic queue mode (queue-mode).
+func (b *PolicyDefinitionBuilder) QueueMode(mode QueueMode) *PolicyDefinitionBuilder {
+	return b.set("queue-mode", string(mode))
+}
+
+// DeliveryLimit sets how many times a message can be redelivered by
+// matching quorum queues before it is dropped or dead-lettered (delivery-limit).
+func (b *PolicyDefinitionBuilder) DeliveryLimit(n int) *PolicyDefinitionBuilder {
+	return b.set("delivery-limit", n)
+}
+
+// QueueMasterLocator sets how the node hosting matching queue masters is picked
+// (queue-master-locator).
+func (b *PolicyDefinitionBuilder) QueueMasterLocator(locator QueueMasterLocator) *PolicyDefinitionBuilder {
+	return b.set("queue-master-locator", string(locator))
+}
+
+// Build returns the policy definition, or the first invalid value or combination of values.
+func (b *PolicyDefinitionBuilder) Build() (PolicyDefinition, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	if err := b.def.Validate(); err != nil {
+		return nil, err
+	}
+
+	def := make(PolicyDefinition, len(b.def))
+	for k, v := range b.def {
+		def[k] = v
+	}
+	return def, nil
+}
+
+// Validate checks the values of well-known policy keys and their combinations.
+// Other keys are not checked.
+func (d PolicyDefinition) Validate() error {
+	mode, hasMode := d["ha-mode"]
+	params, hasParams := d["ha-params"]
+	switch {
+	case !hasMode:
+		if hasParams {
+			return fmt.Errorf("ha-params requires ha-mode")
+		}
+		if _, ok := d["ha-sync-mode"]; ok {
+			return fmt.Errorf("ha-sync-mode requires ha-mode")
+		}
+	case mode == string(HaModeAll):
+		if hasParams {
+			return fmt.Errorf("ha-params is not supported by ha-mode %q", mode)
+		}
+	case mode == string(HaModeExactly):
+		if n, ok := policyInteger(params); !ok || n < 1 {
+			return fmt.Errorf("ha-mode %q requires ha-params to be a positive number of mirrors", mode)
+		}
+	case mode == string(HaModeNodes):
+		if nodes, ok := policyNodeNames(params); !ok || len(nodes) == 0 {
+			return fmt.Errorf("ha-mode %q requires ha-params to be a list of node names", mode)
+		}
+	default:
+		return fmt.Errorf("unknown ha-mode value %v", mode)
+	}
+
+	if v, ok := d["ha-sync-mode"]; ok && v != string(HaSyncModeManual) && v != string(HaSyncModeAutomatic) {
+		return fmt.Errorf("unknown ha-sync-mode value %v", v)
+	}
+
+	_, hasUpstream := d["federation-upstream"]
+	_, hasUpstreamSet := d["federation-upstream-set"]
+	if hasUpstream && hasUpstreamSet {
+		return fmt.Errorf("federation-upstream and federation-upstream-set are mutually exclusive")
+	}
+
+	for _, k := range []string{"message-ttl", "max-length", "max-length-bytes", "delivery-limit"} {
+		if v, ok := d[k]; ok {
+			if n, ok := policyInteger(v); !ok || n < 0 {
+				return fmt.Errorf("%s must be a non-negative integer, got %v", k, v)
+			}
+		}
+	}
+	if v, ok := d["expires"]; ok {
+		if n, ok := policyInteger(v); !ok || n < 1 {
+			return fmt.Errorf("expires must be a positive integer, got %v", v)
+		}
+	}
+
+	if v, ok := d["overflow"]; ok {
+		switch v {
+		case string(OverflowDropHead), string(OverflowRejectPublish), string(OverflowRejectPublishDLX):
+		default:
+			return fmt.Errorf("unknown overflow value %v", v)
+		}
+	}
+	if v, ok := d["queue-mode"]; ok && v != string(QueueModeDefault) && v != string(QueueModeLazy) {
+		return fmt.Errorf("unknown queue-mode value %v", v)
+	}
+	if v, ok := d["queue-master-locator"]; ok {
+		switch v {
+		case string(QueueMasterLocatorMinMasters), string(QueueMasterLocatorClientLocal), string(QueueMasterLocatorRandom):
+		default:
+			return fmt.Errorf("unknown queue-master-locator value %v", v)
+		}
+	}
+
+	return nil
+}
+
+// policyInteger returns v as an integer if it is one, be it a Go integer
+// or a number decoded from JSON.
+func policyInteger(v interface{}) (int64, bool) {
+	switch n := v.(type) {
+	case int:
+		return int64(n), true
+	case int32:
+		return int64(n), true
+	case int64:
+		return n, true
+	case float64:
+		if n == float64(int64(n)) {
+			return int64(n), true
+		}
+	case json.Number:
+		i, err := n.Int64()
+		return i, err == nil
+	}
+	return 0, false
+}
+
+// policyNodeNames returns v as a list of node names if it is one, be it
+// NodeNames or a list decoded from JSON.
+func policyNodeNames(v interface{}) (NodeNames, bool) {
+	switch xs := v.(type) {
+	case NodeNames:
+		return xs, true
+	case []string:
+		return NodeNames(xs), true
+	case []interface{}:
+		nodes := make(NodeNames, 0, len(xs))
+		for _, x := range xs {
+			s, ok := x.(string)
+			if !ok {
+				return nil, false
+			}
+			nodes = append(nodes, s)
+		}
+		return nodes, true
+	}
+	return nil, false
+}
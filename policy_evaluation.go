@@ -0,0 +1,96 @@
+package rabbithole
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Kind of entity a policy is evaluated against.
+type PolicyTargetKind string
+
+const (
+	PolicyTargetQueue    PolicyTargetKind = "queue"
+	PolicyTargetExchange PolicyTargetKind = "exchange"
+)
+
+// PolicyTarget is a queue or exchange to evaluate policies against.
+type PolicyTarget struct {
+	Vhost string
+	Name  string
+	Kind  PolicyTargetKind
+	// Queue type, for policies applying to a single queue type.
+	// Empty means classic
+	QueueType QueueType
+}
+
+// PolicyMatch is the result of evaluating policies against a queue or exchange.
+type PolicyMatch struct {
+	// Policy RabbitMQ would apply, nil if no policy matches
+	Effective *Policy
+	// Other matching policies, shadowed by the effective one,
+	// ordered from the highest to the lowest priority
+	Shadowed []Policy
+}
+
+// EvaluatePolicies returns the policy RabbitMQ would apply to the target, given
+// the policies in its virtual host (e.g. from ListPoliciesIn), as well as all
+// other matching policies it shadows. It can be used to test policy changes before
+// PutPolicy.
+//
+// A policy matches if it is in the target's virtual host, applies to the target's kind
+// and its pattern matches the target's name. The matching policy with the highest
+// priority is applied. RabbitMQ does not define which of several matching policies
+// with the same priority is applied; they are ordered by name here.
+//
+// Patterns are evaluated with Go regular expressions, which differ from the
+// server's PCRE ones in a few rarely used features (e.g. backreferences).
+func EvaluatePolicies(policies []Policy, target PolicyTarget) (PolicyMatch, error) {
+	var matches []Policy
+	for _, p := range policies {
+		if p.Vhost != "" && p.Vhost != target.Vhost {
+			continue
+		}
+		if !policyAppliesTo(p.ApplyTo, target) {
+			continue
+		}
+
+		re, err := compileBrokerPattern(p.Pattern)
+		if err != nil {
+			return PolicyMatch{}, fmt.Errorf("invalid pattern %q of policy %s: %s", p.Pattern, p.Name, err)
+		}
+		if re.MatchString(target.Name) {
+			matches = append(matches, p)
+		}
+	}
+
+	if len(matches) == 0 {
+		return PolicyMatch{}, nil
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].Priority != matches[j].Priority {
+			return matches[i].Priority > matches[j].Priority
+		}
+		return matches[i].Name < matches[j].Name
+	})
+
+	return PolicyMatch{Effective: &matches[0], Shadowed: matches[1:]}, nil
+}
+
+func policyAppliesTo(applyTo string, target PolicyTarget) bool {
+	switch applyTo {
+	case "", "all":
+		return true
+	case "exchanges":
+		return target.Kind == PolicyTargetExchange
+	case "queues":
+		return target.Kind == PolicyTargetQueue
+	case "classic_queues":
+		return target.Kind == PolicyTargetQueue && (target.QueueType == "" || target.QueueType == QueueTypeClassic)
+	case "quorum_queues":
+		return target.Kind == PolicyTargetQueue && target.QueueType == QueueTypeQuorum
+	case "streams":
+		return target.Kind == PolicyTargetQueue && target.QueueType == QueueTypeStream
+	}
+	return false
+}
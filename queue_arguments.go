@@ -0,0 +1,173 @@
+package rabbithole
+
+import (
+	"fmt"
+	"time"
+)
+
+// Behaviour of a queue when its max length is reached (x-overflow).
+type QueueOverflow string
+
+const (
+	// Drop (or dead-letter) messages from the head of the queue
+	OverflowDropHead QueueOverflow = "drop-head"
+	// Reject new publishes
+	OverflowRejectPublish QueueOverflow = "reject-publish"
+	// Reject new publishes and dead-letter them
+	OverflowRejectPublishDLX QueueOverflow = "reject-publish-dlx"
+)
+
+// Classic queue mode (x-queue-mode).
+type QueueMode string
+
+const (
+	QueueModeDefault QueueMode = "default"
+	// Keep as many messages as possible on disk
+	QueueModeLazy QueueMode = "lazy"
+)
+
+// QueueArguments builds well-known optional queue arguments with the types
+// RabbitMQ expects. Invalid values are reported by Build:
+//
+//	args, err := NewQueueArguments().
+//		MessageTTL(30 * time.Second).
+//		MaxLength(10000).
+//		Overflow(OverflowRejectPublish).
+//		DeadLetterExchange("dlx").
+//		Build()
+//
+//	resp, err := rmqc.DeclareQueue("/", "a.queue", QueueSettings{Durable: true, Arguments: args})
+type QueueArguments struct {
+	args map[string]interface{}
+	err  error
+}
+
+// NewQueueArguments returns an empty QueueArguments builder.
+func NewQueueArguments() *QueueArguments {
+	return &QueueArguments{args: make(map[string]interface{})}
+}
+
+func (qa *QueueArguments) set(key string, value interface{}) *QueueArguments {
+	qa.args[key] = value
+	return qa
+}
+
+func (qa *QueueArguments) fail(format string, a ...interface{}) *QueueArguments {
+	if qa.err == nil {
+		qa.err = fmt.Errorf(format, a...)
+	}
+	return qa
+}
+
+// MessageTTL sets how long messages can stay in the queue (x-message-ttl).
+// The TTL is sent in milliseconds.
+func (qa *QueueArguments) MessageTTL(ttl time.Duration) *QueueArguments {
+	if ttl < 0 {
+		return qa.fail("x-message-ttl must not be negative, got %s", ttl)
+	}
+	return qa.set("x-message-ttl", int64(ttl/time.Millisecond))
+}
+
+// Expires sets how long the queue can stay unused before it is deleted (x-expires).
+// The value is sent in milliseconds.
+func (qa *QueueArguments) Expires(after time.Duration) *QueueArguments {
+	if after < time.Millisecond {
+		return qa.fail("x-expires must be at least 1ms, got %s", after)
+	}
+	return qa.set("x-expires", int64(after/time.Millisecond))
+}
+
+// MaxLength sets the maximum number of ready messages in the queue (x-max-length).
+func (qa *QueueArguments) MaxLength(n int64) *QueueArguments {
+	if n < 0 {
+		return qa.fail("x-max-length must not be negative, got %d", n)
+	}
+	return qa.set("x-max-length", n)
+}
+
+// MaxLengthBytes sets the maximum total size of ready messages in the queue (x-max-length-bytes).
+func (qa *QueueArguments) MaxLengthBytes(n int64) *QueueArguments {
+	if n < 0 {
+		return qa.fail("x-max-length-bytes must not be negative, got %d", n)
+	}
+	return qa.set("x-max-length-bytes", n)
+}
+
+// Overflow sets what happens when the queue reaches its max length (x-overflow).
+func (qa *QueueArguments) Overflow(o QueueOverflow) *QueueArguments {
+	switch o {
+	case OverflowDropHead, OverflowRejectPublish, OverflowRejectPublishDLX:
+		return qa.set("x-overflow", string(o))
+	}
+	return qa.fail("unknown x-overflow value %q", o)
+}
+
+// DeadLetterExchange sets the exchange dead-lettered messages are republished to
+// (x-dead-letter-exchange). An empty name is the default exchange.
+func (qa *QueueArguments) DeadLetterExchange(exchange string) *QueueArguments {
+	return qa.set("x-dead-letter-exchange", exchange)
+}
+
+// DeadLetterRoutingKey sets the routing key dead-lettered messages are republished with
+// (x-dead-letter-routing-key). Requires a dead letter exchange.
+func (qa *QueueArguments) DeadLetterRoutingKey(routingKey string) *QueueArguments {
+	return qa.set("x-dead-letter-routing-key", routingKey)
+}
+
+// MaxPriority makes the queue a priority queue supporting priorities
+// from 0 to max (x-max-priority). Classic queues only.
+func (qa *QueueArguments) MaxPriority(max int) *QueueArguments {
+	if max < 1 || max > 255 {
+		return qa.fail("x-max-priority must be between 1 and 255, got %d", max)
+	}
+	return qa.set("x-max-priority", max)
+}
+
+// QueueMode sets the classic queue mode (x-queue-mode).
+func (qa *QueueArguments) QueueMode(mode QueueMode) *QueueArguments {
+	switch mode {
+	case QueueModeDefault, QueueModeLazy:
+		return qa.set("x-queue-mode", string(mode))
+	}
+	return qa.fail("unknown x-queue-mode value %q", mode)
+}
+
+// SingleActiveConsumer enables or disables single active consumer (x-single-active-consumer).
+func (qa *QueueArguments) SingleActiveConsumer(enabled bool) *QueueArguments {
+	return qa.set("x-single-active-consumer", enabled)
+}
+
+// QueueType sets the queue type (x-queue-type).
+func (qa *QueueArguments) QueueType(t QueueType) *QueueArguments {
+	switch t {
+	case QueueTypeClassic, QueueTypeQuorum, QueueTypeStream:
+		return qa.set("x-queue-type", string(t))
+	}
+	return qa.fail("unknown x-queue-type value %q", t)
+}
+
+// Build returns the arguments, or the first invalid value or combination of values.
+func (qa *QueueArguments) Build() (map[string]interface{}, error) {
+	if qa.err != nil {
+		return nil, qa.err
+	}
+
+	_, hasDLX := qa.args["x-dead-letter-exchange"]
+	if _, ok := qa.args["x-dead-letter-routing-key"]; ok && !hasDLX {
+		return nil, fmt.Errorf("x-dead-letter-routing-key requires x-dead-letter-exchange")
+	}
+
+	if t, ok := qa.args["x-queue-type"]; ok && t != string(QueueTypeClassic) {
+		for _, k := range []string{"x-max-priority", "x-queue-mode"} {
+			if _, ok := qa.args[k]; ok {
+				return nil, fmt.Errorf("%s is not supported by %s queues", k, t)
+			}
+		}
+	}
+
+	args := make(map[string]interface{}, len(qa.args))
+	for k, v := range qa.args {
+		args[k] = v
+	}
+	return args, nil
+}
@@ -97,8 +97,32 @@ type QueueInfo struct {
 	BackingQueueStatus BackingQueueStatus `json:"backing_queue_status"`
 	
 	ActiveConsumers int64 `json:"active_consumers"`	
+
+	// Queue type: classic, quorum or stream
+	Type QueueType `json:"type"`
+	// Quorum queue leader (replicated queues only)
+	Leader string `json:"leader,omitempty"`
+	// Nodes hosting a replica of this queue (replicated queues only)
+	Members []string `json:"members,omitempty"`
+	// Nodes hosting a replica of this queue that are online (replicated queues only)
+	OnlineMembers []string `json:"online,omitempty"`
+	// Number of segment files open per node (quorum queues only)
+	OpenFiles map[string]int `json:"open_files,omitempty"`
+	// Number of segment files (streams only)
+	Segments int `json:"segments,omitempty"`
+	// Number of readers per node (streams only)
+	Readers map[string]int `json:"readers,omitempty"`
 }
 
+// Queue type, see QueueSettings.Type.
+type QueueType string
+
+const (
+	QueueTypeClassic QueueType = "classic"
+	QueueTypeQuorum  QueueType = "quorum"
+	QueueTypeStream  QueueType = "stream"
+)
+
 type PagedQueueInfo struct {
 	Page          int         `json:"page"`
 	PageCount     int         `json:"page_count"`
@@ -180,6 +204,9 @@ type DetailedQueueInfo QueueInfo
 // ]
 
 func (c *Client) ListQueues() (rec []QueueInfo, err error) {
+	c, op := c.startOperation("ListQueues", "", "")
+	defer op.end(&err)
+
 	req, err := newGETRequest(c, "queues")
 	if err != nil {
 		return []QueueInfo{}, err
@@ -193,6 +220,9 @@ func (c *Client) ListQueues() (rec []QueueInfo, err error) {
 }
 
 func (c *Client) ListQueuesWithParameters(params url.Values) (rec []QueueInfo, err error) {
+	c, op := c.startOperation("ListQueuesWithParameters", "", "")
+	defer op.end(&err)
+
 	req, err := newGETRequestWithParameters(c, "queues", params)
 	if err != nil {
 		return []QueueInfo{}, err
@@ -206,6 +236,9 @@ func (c *Client) ListQueuesWithParameters(params url.Values) (rec []QueueInfo, e
 }
 
 func (c *Client) PagedListQueuesWithParameters(params url.Values) (rec PagedQueueInfo, err error) {
+	c, op := c.startOperation("PagedListQueuesWithParameters", "", "")
+	defer op.end(&err)
+
 	req, err := newGETRequestWithParameters(c, "queues", params)
 	if err != nil {
 		return PagedQueueInfo{}, err
@@ -224,6 +257,9 @@ func (c *Client) PagedListQueuesWithParameters(params url.Values) (rec PagedQueu
 //
 
 func (c *Client) ListQueuesIn(vhost string) (rec []QueueInfo, err error) {
+	c, op := c.startOperation("ListQueuesIn", vhost, "")
+	defer op.end(&err)
+
 	req, err := newGETRequest(c, "queues/"+PathEscape(vhost))
 	if err != nil {
 		return []QueueInfo{}, err
@@ -241,6 +277,9 @@ func (c *Client) ListQueuesIn(vhost string) (rec []QueueInfo, err error) {
 //
 
 func (c *Client) GetQueue(vhost, queue string) (rec *DetailedQueueInfo, err error) {
+	c, op := c.startOperation("GetQueue", vhost, queue)
+	defer op.end(&err)
+
 	req, err := newGETRequest(c, "queues/"+PathEscape(vhost)+"/"+PathEscape(queue))
 
 	if err != nil {
@@ -258,6 +297,9 @@ func (c *Client) GetQueue(vhost, queue string) (rec *DetailedQueueInfo, err erro
 // GET /api/queues/{vhost}/{name}?{query}
 
 func (c *Client) GetQueueWithParameters(vhost, queue string, qs url.Values) (rec *DetailedQueueInfo, err error) {
+	c, op := c.startOperation("GetQueueWithParameters", vhost, queue)
+	defer op.end(&err)
+
 	req, err := newGETRequestWithParameters(c, "queues/"+PathEscape(vhost)+"/"+PathEscape(queue), qs)
 	if err != nil {
 		return nil, err
@@ -271,20 +313,38 @@ func (c *Client) GetQueueWithParameters(vhost, queue string, qs url.Values) (rec
 }
 
 //
-// PUT /api/exchanges/{vhost}/{exchange}
+// PUT /api/queues/{vhost}/{queue}
 //
 
 type QueueSettings struct {
-	Type       string                 `json:"type"`
+	// Queue type. When set, it is passed to RabbitMQ as the x-queue-type argument,
+	// unless the arguments already have one. Quorum queues and streams must be durable.
+	Type       QueueType              `json:"type"`
 	Durable    bool                   `json:"durable"`
 	AutoDelete bool                   `json:"auto_delete,omitempty"`
 	Arguments  map[string]interface{} `json:"arguments,omitempty"`
 }
 
 func (c *Client) DeclareQueue(vhost, queue string, info QueueSettings) (res *http.Response, err error) {
+	c, op := c.startOperation("DeclareQueue", vhost, queue)
+	defer op.end(&err)
+
+	return c.declareQueue(vhost, queue, info)
+}
+
+func (c *Client) declareQueue(vhost, queue string, info QueueSettings) (res *http.Response, err error) {
 	if info.Arguments == nil {
 		info.Arguments = make(map[string]interface{})
 	}
+	if _, ok := info.Arguments["x-queue-type"]; !ok && info.Type != "" {
+		// copy to avoid modifying the caller's map
+		args := make(map[string]interface{}, len(info.Arguments)+1)
+		for k, v := range info.Arguments {
+			args[k] = v
+		}
+		args["x-queue-type"] = string(info.Type)
+		info.Arguments = args
+	}
 	body, err := json.Marshal(info)
 	if err != nil {
 		return nil, err
@@ -308,6 +368,9 @@ func (c *Client) DeclareQueue(vhost, queue string, info QueueSettings) (res *htt
 //
 
 func (c *Client) DeleteQueue(vhost, queue string) (res *http.Response, err error) {
+	c, op := c.startOperation("DeleteQueue", vhost, queue)
+	defer op.end(&err)
+
 	req, err := newRequestWithBody(c, "DELETE", "queues/"+PathEscape(vhost)+"/"+PathEscape(queue), nil)
 	if err != nil {
 		return nil, err
@@ -326,6 +389,9 @@ func (c *Client) DeleteQueue(vhost, queue string) (res *http.Response, err error
 //
 
 func (c *Client) PurgeQueue(vhost, queue string) (res *http.Response, err error) {
+	c, op := c.startOperation("PurgeQueue", vhost, queue)
+	defer op.end(&err)
+
 	req, err := newRequestWithBody(c, "DELETE", "queues/"+PathEscape(vhost)+"/"+PathEscape(queue)+"/contents", nil)
 	if err != nil {
 		return nil, err
@@ -338,3 +404,121 @@ func (c *Client) PurgeQueue(vhost, queue string) (res *http.Response, err error)
 
 	return res, nil
 }
+
+//
+// POST /api/queues/quorum/{vhost}/{name}/replicas/add
+//
+
+type quorumQueueReplica struct {
+	Node string `json:"node"`
+}
+
+// AddQuorumQueueReplica adds a replica of a quorum queue on the given node.
+func (c *Client) AddQuorumQueueReplica(vhost, queue, node string) (res *http.Response, err error) {
+	c, op := c.startOperation("AddQuorumQueueReplica", vhost, queue)
+	defer op.end(&err)
+
+	return c.changeQuorumQueueReplica("POST", vhost, queue, "add", node)
+}
+
+//
+// DELETE /api/queues/quorum/{vhost}/{name}/replicas/delete
+//
+
+// DeleteQuorumQueueReplica removes the replica of a quorum queue on the given node.
+func (c *Client) DeleteQuorumQueueReplica(vhost, queue, node string) (res *http.Response, err error) {
+	c, op := c.startOperation("DeleteQuorumQueueReplica", vhost, queue)
+	defer op.end(&err)
+
+	return c.changeQuorumQueueReplica("DELETE", vhost, queue, "delete", node)
+}
+
+func (c *Client) changeQuorumQueueReplica(method, vhost, queue, action, node string) (res *http.Response, err error) {
+	body, err := json.Marshal(quorumQueueReplica{Node: node})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := newRequestWithBody(c, method, "queues/quorum/"+PathEscape(vhost)+"/"+PathEscape(queue)+"/replicas/"+action, body)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err = executeRequest(c, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+//
+// POST /api/queues/quorum/replicas/on/{node}/grow
+//
+
+// Which quorum queues to grow.
+type QuorumQueueGrowStrategy string
+
+const (
+	// Grow all matching quorum queues
+	GrowAll QuorumQueueGrowStrategy = "all"
+	// Grow only matching quorum queues with an even number of replicas
+	GrowEven QuorumQueueGrowStrategy = "even"
+)
+
+// Settings used to grow quorum queues onto a node.
+type QuorumQueueGrowSettings struct {
+	// Regular expression matching virtual hosts, e.g. ".*"
+	VhostPattern string `json:"vhost_pattern"`
+	// Regular expression matching queue names, e.g. ".*"
+	QueuePattern string `json:"queue_pattern"`
+	// Which matching queues to grow
+	Strategy QuorumQueueGrowStrategy `json:"strategy"`
+}
+
+// GrowQuorumQueueReplicas adds a replica on the given node to all quorum queues
+// matching the settings.
+func (c *Client) GrowQuorumQueueReplicas(node string, settings QuorumQueueGrowSettings) (res *http.Response, err error) {
+	c, op := c.startOperation("GrowQuorumQueueReplicas", "", node)
+	defer op.end(&err)
+
+	body, err := json.Marshal(settings)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := newRequestWithBody(c, "POST", "queues/quorum/replicas/on/"+PathEscape(node)+"/grow", body)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err = executeRequest(c, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+//
+// DELETE /api/queues/quorum/replicas/on/{node}/shrink
+//
+
+// ShrinkQuorumQueueReplicas removes the replicas on the given node from all quorum queues,
+// e.g. before the node is decommissioned.
+func (c *Client) ShrinkQuorumQueueReplicas(node string) (res *http.Response, err error) {
+	c, op := c.startOperation("ShrinkQuorumQueueReplicas", "", node)
+	defer op.end(&err)
+
+	req, err := newRequestWithBody(c, "DELETE", "queues/quorum/replicas/on/"+PathEscape(node)+"/shrink", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err = executeRequest(c, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
@@ -1,12 +1,22 @@
 package rabbithole
 
 import (
+	"context"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io/ioutil"
+	"net/http"
 	"net/url"
+	"os"
+	"path/filepath"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/michaelklishin/rabbit-hole/internal/fakeapi"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	"github.com/streadway/amqp"
@@ -100,9 +110,9 @@ var _ = Describe("Rabbithole", func() {
 			sdu := "amqp://127.0.0.1/%2f"
 
 			shovelDefinition := ShovelDefinition{
-				SourceURI:         ssu,
+				SourceURI:         URISet{ssu},
 				SourceQueue:       "mySourceQueue",
-				DestinationURI:    sdu,
+				DestinationURI:    URISet{sdu},
 				DestinationQueue:  "myDestQueue",
 				AddForwardHeaders: true,
 				AckMode:           "on-confirm",
@@ -117,13 +127,13 @@ var _ = Describe("Rabbithole", func() {
 			Ω(x.Name).Should(Equal(sn))
 			Ω(x.Vhost).Should(Equal(vh))
 			Ω(x.Component).Should(Equal("shovel"))
-			Ω(x.Definition.SourceURI).Should(Equal(ssu))
+			Ω(x.Definition.SourceURI).Should(Equal(URISet{ssu}))
 			Ω(x.Definition.SourceQueue).Should(Equal("mySourceQueue"))
-			Ω(x.Definition.DestinationURI).Should(Equal(sdu))
+			Ω(x.Definition.DestinationURI).Should(Equal(URISet{sdu}))
 			Ω(x.Definition.DestinationQueue).Should(Equal("myDestQueue"))
 			Ω(x.Definition.AddForwardHeaders).Should(Equal(true))
 			Ω(x.Definition.AckMode).Should(Equal("on-confirm"))
-			Ω(x.Definition.DeleteAfter).Should(Equal("never"))
+			Ω(x.Definition.DeleteAfter).Should(Equal(DeleteAfterNever))
 
 			rmqc.DeleteShovel(vh, sn)
 			awaitEventPropagation()
@@ -132,6 +142,182 @@ var _ = Describe("Rabbithole", func() {
 		})
 	})
 
+	Context("PUT /parameters/shovel/{vhost}/{name} with a v2 definition", func() {
+		It("declares a shovel", func() {
+			vh := "rabbit/hole"
+			sn := "temporary.v2"
+
+			shovelDefinition := ShovelDefinition{
+				SourceProtocol:                ShovelAMQP091,
+				SourceURI:                     URISet{"amqp://127.0.0.1/%2f", "amqp://localhost/%2f"},
+				SourceExchange:                "amq.topic",
+				SourceExchangeKey:             "#",
+				SourcePrefetchCount:           100,
+				SourceDeleteAfter:             DeleteAfterMessages(42),
+				DestinationProtocol:           ShovelAMQP091,
+				DestinationURI:                URISet{"amqp://127.0.0.1/%2f"},
+				DestinationQueue:              "myDestQueue",
+				DestinationAddTimestampHeader: true,
+				DestinationPublishProperties: map[string]interface{}{
+					"delivery_mode": 2,
+				},
+				AckMode: "on-confirm"}
+
+			_, err := rmqc.DeclareShovel(vh, sn, shovelDefinition)
+			Ω(err).Should(BeNil())
+
+			awaitEventPropagation()
+			x, err := rmqc.GetShovel(vh, sn)
+			Ω(err).Should(BeNil())
+			Ω(x.Definition.SourceProtocol).Should(Equal(ShovelAMQP091))
+			Ω(x.Definition.SourceURI).Should(Equal(shovelDefinition.SourceURI))
+			Ω(x.Definition.SourceExchange).Should(Equal("amq.topic"))
+			Ω(x.Definition.SourcePrefetchCount).Should(Equal(100))
+			Ω(x.Definition.SourceDeleteAfter).Should(Equal(DeleteAfterMessages(42)))
+			Ω(x.Definition.DestinationURI).Should(Equal(shovelDefinition.DestinationURI))
+			Ω(x.Definition.DestinationAddTimestampHeader).Should(Equal(true))
+			Ω(x.Definition.DestinationPublishProperties).Should(HaveKeyWithValue("delivery_mode", BeNumerically("==", 2)))
+
+			rmqc.DeleteShovel(vh, sn)
+		})
+	})
+
+	Context("ShovelDefinition", func() {
+		It("marshals delete-after as a number or a string", func() {
+			b, err := json.Marshal(ShovelDefinition{SourceDeleteAfter: DeleteAfterMessages(10)})
+			Ω(err).Should(BeNil())
+			Ω(string(b)).Should(ContainSubstring(`"src-delete-after":10`))
+
+			b, err = json.Marshal(ShovelDefinition{SourceDeleteAfter: DeleteAfterQueueLength})
+			Ω(err).Should(BeNil())
+			Ω(string(b)).Should(ContainSubstring(`"src-delete-after":"queue-length"`))
+		})
+
+		It("unmarshals a single URI or a list of URIs", func() {
+			var d ShovelDefinition
+			err := json.Unmarshal([]byte(`{"src-uri":"amqp://one","dest-uri":["amqp://two","amqp://three"],"src-delete-after":5}`), &d)
+			Ω(err).Should(BeNil())
+			Ω(d.SourceURI).Should(Equal(URISet{"amqp://one"}))
+			Ω(d.DestinationURI).Should(Equal(URISet{"amqp://two", "amqp://three"}))
+			Ω(d.SourceDeleteAfter).Should(Equal(DeleteAfter("5")))
+		})
+
+		It("unmarshals a numeric legacy delete-after", func() {
+			var x ShovelInfo
+			err := json.Unmarshal([]byte(`{"name":"sh","vhost":"/","component":"shovel","value":{"src-uri":"amqp://","src-queue":"a","dest-uri":"amqp://","delete-after":5}}`), &x)
+			Ω(err).Should(BeNil())
+			Ω(x.Definition.DeleteAfter).Should(Equal(DeleteAfterMessages(5)))
+			Ω(x.Definition.Validate()).Should(Succeed())
+		})
+
+		It("validates source and destination options", func() {
+			valid := ShovelDefinition{
+				SourceURI:      URISet{"amqp://"},
+				SourceQueue:    "a.queue",
+				DestinationURI: URISet{"amqp://"},
+			}
+			Ω(valid.Validate()).Should(Succeed())
+
+			d := valid
+			d.SourceExchange = "an.exchange"
+			Ω(d.Validate()).ShouldNot(Succeed())
+
+			d = valid
+			d.SourceQueue = ""
+			Ω(d.Validate()).ShouldNot(Succeed())
+
+			d = valid
+			d.SourceExchangeKey = "#"
+			Ω(d.Validate()).ShouldNot(Succeed())
+
+			d = valid
+			d.DestinationQueue = "a.queue"
+			d.DestinationExchange = "an.exchange"
+			Ω(d.Validate()).ShouldNot(Succeed())
+
+			d = valid
+			d.SourceProtocol = ShovelAMQP10
+			Ω(d.Validate()).ShouldNot(Succeed())
+			d.SourceQueue = ""
+			d.SourceAddress = "/queue/a"
+			Ω(d.Validate()).Should(Succeed())
+
+			d = valid
+			d.DestinationProtocol = ShovelAMQP10
+			Ω(d.Validate()).ShouldNot(Succeed())
+			d.DestinationAddress = "/queue/a"
+			Ω(d.Validate()).Should(Succeed())
+
+			d = valid
+			d.SourceDeleteAfter = "sometimes"
+			Ω(d.Validate()).ShouldNot(Succeed())
+
+			d = valid
+			d.DestinationURI = nil
+			Ω(d.Validate()).ShouldNot(Succeed())
+
+			_, err := rmqc.DeclareShovel("/", "invalid", ShovelDefinition{})
+			Ω(err).Should(HaveOccurred())
+		})
+	})
+
+	Context("GET /shovels/{vhost}", func() {
+		It("returns the status of shovels", func() {
+			vh := "rabbit/hole"
+			sn := "temporary.status"
+
+			_, err := rmqc.DeclareShovel(vh, sn, ShovelDefinition{
+				SourceURI:        URISet{"amqp://127.0.0.1/%2f"},
+				SourceQueue:      "mySourceQueue",
+				DestinationURI:   URISet{"amqp://127.0.0.1/%2f"},
+				DestinationQueue: "myDestQueue",
+				AckMode:          "on-confirm",
+				DeleteAfter:      "never"})
+			Ω(err).Should(BeNil())
+
+			awaitEventPropagation()
+			xs, err := rmqc.ListShovelStatusIn(vh)
+			Ω(err).Should(BeNil())
+			Ω(xs).Should(HaveLen(1))
+
+			x := xs[0]
+			Ω(x.Name).Should(Equal(sn))
+			Ω(x.Vhost).Should(Equal(vh))
+			Ω(x.Type).Should(Equal("dynamic"))
+			Ω(x.State).Should(BeElementOf(ShovelStarting, ShovelRunning))
+			Ω(x.Node).ShouldNot(BeEmpty())
+
+			xs, err = rmqc.ListShovelStatus()
+			Ω(err).Should(BeNil())
+			Ω(xs).ShouldNot(BeEmpty())
+
+			rmqc.DeleteShovel(vh, sn)
+		})
+	})
+
+	Context("DELETE /shovels/vhost/{vhost}/{name}/restart", func() {
+		It("restarts a shovel", func() {
+			vh := "rabbit/hole"
+			sn := "temporary.restart"
+
+			_, err := rmqc.DeclareShovel(vh, sn, ShovelDefinition{
+				SourceURI:        URISet{"amqp://127.0.0.1/%2f"},
+				SourceQueue:      "mySourceQueue",
+				DestinationURI:   URISet{"amqp://127.0.0.1/%2f"},
+				DestinationQueue: "myDestQueue",
+				AckMode:          "on-confirm",
+				DeleteAfter:      "never"})
+			Ω(err).Should(BeNil())
+
+			awaitEventPropagation()
+			res, err := rmqc.RestartShovel(vh, sn)
+			Ω(err).Should(BeNil())
+			Ω(res.StatusCode).Should(Equal(http.StatusNoContent))
+
+			rmqc.DeleteShovel(vh, sn)
+		})
+	})
+
 	Context("GET /overview", func() {
 		It("returns decoded response", func() {
 			conn := openConnection("/")
@@ -186,6 +372,77 @@ var _ = Describe("Rabbithole", func() {
 		})
 	})
 
+	Context("DELETE /api/connections/{name} with a reason", func() {
+		It("closes the connection", func() {
+			listConnectionsUntil(rmqc, 0)
+			conn := openConnection("/")
+
+			awaitEventPropagation()
+			xs, err := rmqc.ListConnections()
+			Ω(err).Should(BeNil())
+
+			closeEvents := make(chan *amqp.Error)
+			conn.NotifyClose(closeEvents)
+
+			n := xs[0].Name
+			rmqc.CloseConnectionWithReason(n, "rotating credentials")
+
+			evt := <-closeEvents
+			Ω(evt).ShouldNot(BeNil())
+			Ω(evt.Code).Should(Equal(320))
+			Ω(evt.Reason).Should(Equal("CONNECTION_FORCED - rotating credentials"))
+			// server-initiated
+			Ω(evt.Server).Should(Equal(true))
+		})
+	})
+
+	Context("GET /api/connections/username/{username}", func() {
+		It("returns decoded response", func() {
+			listConnectionsUntil(rmqc, 0)
+			conn := openConnection("/")
+			defer conn.Close()
+
+			awaitEventPropagation()
+			xs, err := rmqc.ListConnectionsOfUser("guest")
+			Ω(err).Should(BeNil())
+			Ω(xs).Should(HaveLen(1))
+			Ω(xs[0].User).Should(Equal("guest"))
+
+			xs, err = rmqc.ListConnectionsOfUser("a-user-with-no-connections")
+			Ω(err).Should(BeNil())
+			Ω(xs).Should(BeEmpty())
+		})
+	})
+
+	Context("DELETE /api/connections/username/{username}", func() {
+		It("closes all connections of the user", func() {
+			listConnectionsUntil(rmqc, 0)
+			conn := openConnection("/")
+			conn2 := openConnection("rabbit/hole")
+
+			awaitEventPropagation()
+
+			closeEvents := make(chan *amqp.Error)
+			conn.NotifyClose(closeEvents)
+			closeEvents2 := make(chan *amqp.Error)
+			conn2.NotifyClose(closeEvents2)
+
+			rmqc.CloseAllConnectionsOfUser("guest", "rotating credentials")
+
+			for _, ch := range []chan *amqp.Error{closeEvents, closeEvents2} {
+				evt := <-ch
+				Ω(evt).ShouldNot(BeNil())
+				Ω(evt.Code).Should(Equal(320))
+				Ω(evt.Reason).Should(Equal("CONNECTION_FORCED - rotating credentials"))
+			}
+
+			listConnectionsUntil(rmqc, 0)
+			xs, err := rmqc.ListConnectionsOfUser("guest")
+			Ω(err).Should(BeNil())
+			Ω(xs).Should(BeEmpty())
+		})
+	})
+
 	Context("EnabledProtocols", func() {
 		It("returns a list of enabled protocols", func() {
 			xs, err := rmqc.EnabledProtocols()
@@ -457,6 +714,75 @@ var _ = Describe("Rabbithole", func() {
 		})
 	})
 
+	Context("GET /connections/{name}/channels when connection has open channels", func() {
+		It("returns decoded response", func() {
+			conn := openConnection("/")
+			defer conn.Close()
+
+			ch, err := conn.Channel()
+			Ω(err).Should(BeNil())
+			defer ch.Close()
+
+			ch2, err := conn.Channel()
+			Ω(err).Should(BeNil())
+			defer ch2.Close()
+
+			// give internal events a moment to be
+			// handled
+			awaitEventPropagation()
+
+			xs, err := rmqc.ListConnections()
+			Ω(err).Should(BeNil())
+
+			c1 := xs[0]
+			chs, err := rmqc.ListConnectionChannels(c1.Name)
+			Ω(err).Should(BeNil())
+			Ω(chs).Should(HaveLen(2))
+
+			info := chs[0]
+			Ω(info.ConnectionDetails.Name).Should(Equal(c1.Name))
+			Ω(info.User).Should(Equal("guest"))
+			Ω(info.Vhost).Should(Equal("/"))
+		})
+	})
+
+	Context("GET /vhosts/{vhost}/connections when there are active connections", func() {
+		It("returns decoded response", func() {
+			conn := openConnection("rabbit/hole")
+			defer conn.Close()
+
+			// give internal events a moment to be
+			// handled
+			awaitEventPropagation()
+
+			xs, err := rmqc.ListConnectionsIn("rabbit/hole")
+			Ω(err).Should(BeNil())
+			Ω(xs).Should(HaveLen(1))
+			Ω(xs[0].Vhost).Should(Equal("rabbit/hole"))
+		})
+	})
+
+	Context("GET /vhosts/{vhost}/channels when there are active connections with open channels", func() {
+		It("returns decoded response", func() {
+			conn := openConnection("rabbit/hole")
+			defer conn.Close()
+
+			ch, err := conn.Channel()
+			Ω(err).Should(BeNil())
+			defer ch.Close()
+
+			// give internal events a moment to be
+			// handled
+			awaitEventPropagation()
+
+			xs, err := rmqc.ListChannelsIn("rabbit/hole")
+			Ω(err).Should(BeNil())
+			Ω(xs).Should(HaveLen(1))
+			Ω(xs[0].Vhost).Should(Equal("rabbit/hole"))
+			Ω(xs[0].User).Should(Equal("guest"))
+		})
+	})
+
 	Context("GET /exchanges", func() {
 		It("returns decoded response", func() {
 			xs, err := rmqc.ListExchanges()
@@ -764,6 +1090,122 @@ var _ = Describe("Rabbithole", func() {
 		})
 	})
 
+	Context("POST /users/bulk-delete", func() {
+		It("deletes the users", func() {
+			us := []string{"rabbithole.bulk1", "rabbithole.bulk2"}
+			for _, u := range us {
+				_, err := rmqc.PutUser(u, UserSettings{Password: "s3krE7"})
+				Ω(err).Should(BeNil())
+			}
+
+			resp, err := rmqc.DeleteUsers(us)
+			Ω(err).Should(BeNil())
+			Ω(resp.Status).Should(HavePrefix("20"))
+
+			awaitEventPropagation()
+
+			for _, u := range us {
+				_, err := rmqc.GetUser(u)
+				Ω(err).Should(Equal(ErrorResponse{404, "Object Not Found", "Not Found"}))
+			}
+		})
+	})
+
+	Context("GET /users/without-permissions", func() {
+		It("returns users without access to any vhost", func() {
+			u := "rabbithole.nopermissions"
+			_, err := rmqc.PutUser(u, UserSettings{Password: "s3krE7"})
+			Ω(err).Should(BeNil())
+
+			awaitEventPropagation()
+
+			xs, err := rmqc.ListUsersWithoutPermissions()
+			Ω(err).Should(BeNil())
+			Ω(FindUserByName(xs, u).Name).Should(Equal(u))
+			Ω(FindUserByName(xs, "guest").Name).Should(BeEmpty())
+
+			as, err := rmqc.ListUserVhostAccess()
+			Ω(err).Should(BeNil())
+			for _, a := range as {
+				if a.Name == u {
+					Ω(a.HasVhostAccess()).Should(BeFalse())
+				}
+				if a.Name == "guest" {
+					Ω(a.Vhosts).Should(ContainElement("/"))
+				}
+			}
+
+			rmqc.DeleteUser(u)
+		})
+	})
+
+	Context("ListUserVhostAccess", func() {
+		It("joins users with their permissions", func() {
+			api := fakeapi.New().
+				Respond("/api/users/", http.StatusOK, `[{"name":"svc","tags":""},{"name":"admin","tags":"administrator"}]`).
+				Respond("/api/permissions/", http.StatusOK, `[{"user":"admin","vhost":"b","configure":".*","write":".*","read":".*"},{"user":"admin","vhost":"a","configure":".*","write":".*","read":".*"}]`)
+			defer api.Close()
+
+			c, _ := NewClient(api.URL, "guest", "guest")
+			as, err := c.ListUserVhostAccess()
+			Ω(err).Should(BeNil())
+			Ω(as).Should(Equal([]UserVhostAccess{
+				{Name: "admin", Tags: "administrator", Vhosts: []string{"a", "b"}},
+				{Name: "svc", Tags: ""},
+			}))
+			Ω(as[0].HasVhostAccess()).Should(BeTrue())
+			Ω(as[1].HasVhostAccess()).Should(BeFalse())
+		})
+	})
+
+	Context("HashPassword", func() {
+		It("produces hashes in RabbitMQ's format", func() {
+			h, err := HashPasswordWithSalt("test12", []byte{0x90, 0x8D, 0xC6, 0x0A}, HashingAlgorithmSHA256)
+			Ω(err).Should(BeNil())
+			Ω(h).Should(Equal("kI3GCqW5JLMJa4iX1lo7X4D6XbYqlLgxIs30+P6tENUV2POR"))
+
+			h, err = HashPassword("test12", HashingAlgorithmSHA512)
+			Ω(err).Should(BeNil())
+			raw, err := base64.StdEncoding.DecodeString(h)
+			Ω(err).Should(BeNil())
+			Ω(raw).Should(HaveLen(4 + 64))
+
+			h, err = HashPassword("test12", HashingAlgorithmMD5)
+			Ω(err).Should(BeNil())
+			raw, err = base64.StdEncoding.DecodeString(h)
+			Ω(err).Should(BeNil())
+			Ω(raw).Should(HaveLen(4 + 16))
+		})
+
+		It("fails for unknown algorithms", func() {
+			_, err := HashPassword("test12", HashingAlgorithm("rabbit_password_hashing_rot13"))
+			Ω(err).ShouldNot(BeNil())
+		})
+
+		It("creates users that can authenticate with the password", func() {
+			for _, alg := range []HashingAlgorithm{HashingAlgorithmSHA256, HashingAlgorithmSHA512, HashingAlgorithmMD5} {
+				h, err := HashPassword("s3krE7", alg)
+				Ω(err).Should(BeNil())
+
+				_, err = rmqc.PutUser("rabbithole.hashed", UserSettings{PasswordHash: h, HashingAlgorithm: alg, Tags: "management"})
+				Ω(err).Should(BeNil())
+
+				awaitEventPropagation()
+				u, err := rmqc.GetUser("rabbithole.hashed")
+				Ω(err).Should(BeNil())
+				Ω(u.PasswordHash).Should(Equal(h))
+				Ω(u.HashingAlgorithm).Should(Equal(alg))
+
+				c, err := NewClient("http://127.0.0.1:15672", "rabbithole.hashed", "s3krE7")
+				Ω(err).Should(BeNil())
+				_, err = c.Overview()
+				Ω(err).Should(BeNil())
+			}
+
+			rmqc.DeleteUser("rabbithole.hashed")
+		})
+	})
+
 	Context("DELETE /users/{name}", func() {
 		It("deletes the user", func() {
 			info := UserSettings{Password: "s3krE7", Tags: "management policymaker"}
@@ -786,47 +1228,234 @@ var _ = Describe("Rabbithole", func() {
 		})
 	})
 
-	Context("GET /vhosts", func() {
+	Context("GET /aliveness-test/{vhost}", func() {
 		It("returns decoded response", func() {
-			xs, err := rmqc.ListVhosts()
+			res, err := rmqc.Aliveness("rabbit/hole")
 			Ω(err).Should(BeNil())
+			Ω(res.Status).Should(Equal("ok"))
+			Ω(res.Ok()).Should(BeTrue())
+		})
 
-			x := xs[0]
-			Ω(x.Name).ShouldNot(BeNil())
-			Ω(x.Tracing).ShouldNot(BeNil())
+		It("fails for a missing vhost", func() {
+			_, err := rmqc.Aliveness("a-missing-vhost")
+			Ω(err).Should(HaveOccurred())
 		})
 	})
 
-	Context("GET /vhosts/{name} when vhost exists", func() {
-		It("returns decoded response", func() {
-			x, err := rmqc.GetVhost("rabbit/hole")
+	Context("GET /health/checks/alarms", func() {
+		It("passes when no alarms are in effect", func() {
+			res, err := rmqc.CheckAlarms()
 			Ω(err).Should(BeNil())
-
-			Ω(x.Name).ShouldNot(BeNil())
-			Ω(x.Tracing).ShouldNot(BeNil())
+			Ω(res.Ok()).Should(BeTrue())
+			Ω(res.Alarms).Should(BeEmpty())
 		})
 	})
 
-	Context("PUT /vhosts/{name}", func() {
-		It("creates a vhost", func() {
-			vs := VhostSettings{Tracing: false}
-			_, err := rmqc.PutVhost("rabbit/hole2", vs)
+	Context("GET /health/checks/local-alarms", func() {
+		It("passes when no alarms are in effect", func() {
+			res, err := rmqc.CheckLocalAlarms()
 			Ω(err).Should(BeNil())
+			Ω(res.Ok()).Should(BeTrue())
+		})
+	})
 
-			awaitEventPropagation()
-			x, err := rmqc.GetVhost("rabbit/hole2")
+	Context("GET /health/checks/certificate-expiration/{within}/{unit}", func() {
+		It("passes when TLS is not used", func() {
+			res, err := rmqc.CheckCertificateExpiration(1, TimeUnitMonths)
+			Ω(err).Should(BeNil())
+			Ω(res.Ok()).Should(BeTrue())
+		})
+	})
 
-			Ω(x.Name).Should(BeEquivalentTo("rabbit/hole2"))
-			Ω(x.Tracing).Should(Equal(false))
+	Context("GET /health/checks/port-listener/{port}", func() {
+		It("passes for a port with a listener", func() {
+			res, err := rmqc.CheckPortListener(5672)
+			Ω(err).Should(BeNil())
+			Ω(res.Ok()).Should(BeTrue())
+			Ω(res.Port).Should(BeEquivalentTo(5672))
+		})
 
-			rmqc.DeleteVhost("rabbit/hole2")
+		It("fails for a port without a listener", func() {
+			res, err := rmqc.CheckPortListener(1234)
+			Ω(err).Should(BeNil())
+			Ω(res.Ok()).Should(BeFalse())
+			Ω(res.Missing).Should(BeEquivalentTo(1234))
+			Ω(res.Ports).Should(ContainElement(Port(5672)))
 		})
 	})
 
-	Context("DELETE /vhosts/{name}", func() {
-		It("creates a vhost", func() {
-			vs := VhostSettings{Tracing: false}
-			_, err := rmqc.PutVhost("rabbit/hole2", vs)
+	Context("GET /health/checks/protocol-listener/{protocol}", func() {
+		It("passes for a protocol with a listener", func() {
+			res, err := rmqc.CheckProtocolListener("amqp")
+			Ω(err).Should(BeNil())
+			Ω(res.Ok()).Should(BeTrue())
+			Ω(res.Protocol).Should(Equal("amqp"))
+		})
+
+		It("fails for a protocol without a listener", func() {
+			res, err := rmqc.CheckProtocolListener("amqp/ssl")
+			Ω(err).Should(BeNil())
+			Ω(res.Ok()).Should(BeFalse())
+			Ω(res.Missing).Should(Equal("amqp/ssl"))
+			Ω(res.Protocols).Should(ContainElement("amqp"))
+		})
+	})
+
+	Context("GET /health/checks/virtual-hosts", func() {
+		It("passes when all vhosts are running", func() {
+			res, err := rmqc.CheckVirtualHosts()
+			Ω(err).Should(BeNil())
+			Ω(res.Ok()).Should(BeTrue())
+		})
+	})
+
+	Context("GET /health/checks/node-is-quorum-critical", func() {
+		It("passes on a single node", func() {
+			res, err := rmqc.CheckIfNodeIsQuorumCritical()
+			Ω(err).Should(BeNil())
+			Ω(res.Ok()).Should(BeTrue())
+		})
+	})
+
+	Context("GET /health/checks/node-is-mirror-sync-critical", func() {
+		It("passes on a single node", func() {
+			res, err := rmqc.CheckIfNodeIsMirrorSyncCritical()
+			Ω(err).Should(BeNil())
+			Ω(res.Ok()).Should(BeTrue())
+		})
+	})
+
+	Context("GET /health/checks/* responses", func() {
+		It("decodes a failed check from a 503 response", func() {
+			api := fakeapi.New().Respond("/api/health/checks/alarms", http.StatusServiceUnavailable,
+				`{"status":"failed","reason":"There are alarms in effect in the cluster","alarms":[{"node":"rabbit@hostname","resource":"memory"}]}`)
+			defer api.Close()
+
+			c, _ := NewClient(api.URL, "guest", "guest")
+			res, err := c.CheckAlarms()
+			Ω(err).Should(BeNil())
+			Ω(res.Ok()).Should(BeFalse())
+			Ω(res.Reason).Should(Equal("There are alarms in effect in the cluster"))
+			Ω(res.Alarms).Should(Equal([]HealthCheckAlarm{{Node: "rabbit@hostname", Resource: "memory"}}))
+		})
+
+		It("returns other error responses as errors", func() {
+			api := fakeapi.New().HandleOthers(fakeapi.JSON(http.StatusUnauthorized, `{"error":"not_authorised","reason":"Login failed"}`))
+			defer api.Close()
+
+			c, _ := NewClient(api.URL, "guest", "guest")
+			_, err := c.CheckAlarms()
+			Ω(err).Should(HaveOccurred())
+			Ω(err.(ErrorResponse).StatusCode).Should(Equal(http.StatusUnauthorized))
+		})
+	})
+
+	Context("CheckAllVhosts", func() {
+		It("reports no failures when all vhosts are alive", func() {
+			failures, err := rmqc.CheckAllVhosts()
+			Ω(err).Should(BeNil())
+			Ω(failures).Should(BeEmpty())
+		})
+
+		It("limits the number of aliveness tests run at a time", func() {
+			var (
+				mu               sync.Mutex
+				current, maxSeen int
+			)
+			vhosts := make([]string, 10)
+			for i := range vhosts {
+				vhosts[i] = fmt.Sprintf(`{"name":"vh%d"}`, i)
+			}
+			arrived := make(chan struct{}, len(vhosts))
+			release := make(chan struct{})
+			api := fakeapi.New().Respond("/api/vhosts", http.StatusOK, "["+strings.Join(vhosts, ",")+"]")
+			defer api.Close()
+			api.HandleOthers(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				current++
+				if current > maxSeen {
+					maxSeen = current
+				}
+				mu.Unlock()
+				arrived <- struct{}{}
+				<-release
+				mu.Lock()
+				current--
+				mu.Unlock()
+				if r.URL.Path == "/api/aliveness-test/vh3" {
+					fakeapi.JSON(http.StatusOK, `{"status":"failed","reason":"boom"}`)(w, r)
+					return
+				}
+				fakeapi.JSON(http.StatusOK, `{"status":"ok"}`)(w, r)
+			})
+
+			c, _ := NewClient(api.URL, "guest", "guest")
+			done := make(chan []VhostAlivenessFailure)
+			go func() {
+				defer GinkgoRecover()
+				failures, err := c.CheckAllVhostsWithConcurrency(3)
+				Ω(err).Should(BeNil())
+				done <- failures
+			}()
+			for i := 0; i < 3; i++ {
+				Eventually(arrived).Should(Receive())
+			}
+			Consistently(arrived, 50*time.Millisecond).ShouldNot(Receive())
+			close(release)
+
+			var failures []VhostAlivenessFailure
+			Eventually(done).Should(Receive(&failures))
+			Ω(failures).Should(HaveLen(1))
+			Ω(failures[0].Vhost).Should(Equal("vh3"))
+			Ω(api.Requests()).Should(HaveLen(11))
+			Ω(maxSeen).Should(BeNumerically("<=", 3))
+
+			_, err := c.CheckAllVhostsWithConcurrency(0)
+			Ω(err).Should(HaveOccurred())
+		})
+	})
+
+	Context("GET /vhosts", func() {
+		It("returns decoded response", func() {
+			xs, err := rmqc.ListVhosts()
+			Ω(err).Should(BeNil())
+
+			x := xs[0]
+			Ω(x.Name).ShouldNot(BeNil())
+			Ω(x.Tracing).ShouldNot(BeNil())
+		})
+	})
+
+	Context("GET /vhosts/{name} when vhost exists", func() {
+		It("returns decoded response", func() {
+			x, err := rmqc.GetVhost("rabbit/hole")
+			Ω(err).Should(BeNil())
+
+			Ω(x.Name).ShouldNot(BeNil())
+			Ω(x.Tracing).ShouldNot(BeNil())
+		})
+	})
+
+	Context("PUT /vhosts/{name}", func() {
+		It("creates a vhost", func() {
+			vs := VhostSettings{Tracing: false}
+			_, err := rmqc.PutVhost("rabbit/hole2", vs)
+			Ω(err).Should(BeNil())
+
+			awaitEventPropagation()
+			x, err := rmqc.GetVhost("rabbit/hole2")
+
+			Ω(x.Name).Should(BeEquivalentTo("rabbit/hole2"))
+			Ω(x.Tracing).Should(Equal(false))
+
+			rmqc.DeleteVhost("rabbit/hole2")
+		})
+	})
+
+	Context("DELETE /vhosts/{name}", func() {
+		It("creates a vhost", func() {
+			vs := VhostSettings{Tracing: false}
+			_, err := rmqc.PutVhost("rabbit/hole2", vs)
 			Ω(err).Should(BeNil())
 
 			awaitEventPropagation()
@@ -1048,6 +1677,238 @@ var _ = Describe("Rabbithole", func() {
 		})
 	})
 
+	Context("DeclareQueueBinding", func() {
+		It("declares a binding and returns its properties key", func() {
+			vh := "rabbit/hole"
+			qn := "test.bindings.declare.queue"
+
+			_, err := rmqc.DeclareQueue(vh, qn, QueueSettings{})
+			Ω(err).Should(BeNil())
+
+			pk, err := rmqc.DeclareQueueBinding(vh, "amq.topic", qn, "a.#", nil)
+			Ω(err).Should(BeNil())
+
+			awaitEventPropagation()
+			bs, err := rmqc.ListQueueBindingsBetween(vh, "amq.topic", qn)
+			Ω(err).Should(BeNil())
+			Ω(bs).Should(HaveLen(1))
+			Ω(bs[0].RoutingKey).Should(Equal("a.#"))
+			Ω(bs[0].PropertiesKey).Should(Equal(pk))
+
+			_, err = rmqc.DeleteBinding(vh, bs[0])
+			Ω(err).Should(BeNil())
+
+			awaitEventPropagation()
+			bs, err = rmqc.ListQueueBindingsBetween(vh, "amq.topic", qn)
+			Ω(err).Should(BeNil())
+			Ω(bs).Should(BeEmpty())
+
+			rmqc.DeleteQueue(vh, qn)
+		})
+
+		It("fails when the queue does not exist", func() {
+			_, err := rmqc.DeclareQueueBinding("rabbit/hole", "amq.topic", "test.bindings.missing.queue", "#", nil)
+			Ω(err).Should(HaveOccurred())
+			Ω(err.(ErrorResponse).StatusCode).Should(Equal(404))
+		})
+	})
+
+	Context("DeclareExchangeToExchangeBinding", func() {
+		It("declares a binding and returns its properties key", func() {
+			vh := "rabbit/hole"
+			xn := "test.bindings.declare.exchange"
+
+			_, err := rmqc.DeclareExchange(vh, xn, ExchangeSettings{Type: "topic"})
+			Ω(err).Should(BeNil())
+
+			pk, err := rmqc.DeclareExchangeToExchangeBinding(vh, "amq.topic", xn, "a.#", map[string]interface{}{
+				"one": "two",
+			})
+			Ω(err).Should(BeNil())
+
+			awaitEventPropagation()
+			bs, err := rmqc.ListExchangeBindingsBetween(vh, "amq.topic", xn)
+			Ω(err).Should(BeNil())
+			Ω(bs).Should(HaveLen(1))
+			Ω(bs[0].PropertiesKey).Should(Equal(pk))
+
+			_, err = rmqc.DeleteBinding(vh, BindingInfo{
+				Source:          "amq.topic",
+				Destination:     xn,
+				DestinationType: "exchange",
+				PropertiesKey:   pk,
+			})
+			Ω(err).Should(BeNil())
+
+			awaitEventPropagation()
+			bs, err = rmqc.ListExchangeBindingsBetween(vh, "amq.topic", xn)
+			Ω(err).Should(BeNil())
+			Ω(bs).Should(BeEmpty())
+
+			rmqc.DeleteExchange(vh, xn)
+		})
+	})
+
+	Context("DeclareBinding with an invalid destination type", func() {
+		It("returns an error", func() {
+			_, err := rmqc.DeclareBinding("rabbit/hole", BindingInfo{
+				Source:      "amq.topic",
+				Destination: "a.queue",
+			})
+			Ω(err).Should(HaveOccurred())
+
+			_, err = rmqc.DeleteBinding("rabbit/hole", BindingInfo{
+				Source:          "amq.topic",
+				Destination:     "a.queue",
+				DestinationType: "topic",
+			})
+			Ω(err).Should(HaveOccurred())
+		})
+	})
+
+	Context("DeclareBinding with a short destination type", func() {
+		It("accepts q and e", func() {
+			api := fakeapi.New().HandleOthers(fakeapi.JSON(http.StatusCreated, ``))
+			defer api.Close()
+
+			c, _ := NewClient(api.URL, "guest", "guest")
+			_, err := c.DeclareBinding("rabbit/hole", BindingInfo{Source: "amq.topic", Destination: "a.queue", DestinationType: "q"})
+			Ω(err).Should(BeNil())
+			_, err = c.DeleteBinding("rabbit/hole", BindingInfo{Source: "amq.topic", Destination: "an.exchange", DestinationType: "e", PropertiesKey: "~"})
+			Ω(err).Should(BeNil())
+
+			rs := api.Requests()
+			Ω(rs).Should(HaveLen(2))
+			Ω(rs[0].Path).Should(Equal("/api/bindings/rabbit%2Fhole/e/amq.topic/q/a.queue"))
+			Ω(rs[1].Path).Should(Equal("/api/bindings/rabbit%2Fhole/e/amq.topic/e/an.exchange/~"))
+		})
+	})
+
+	Context("listing bindings of the default exchange", func() {
+		It("addresses it as amq.default", func() {
+			api := fakeapi.New().HandleOthers(fakeapi.JSON(http.StatusOK, `[]`))
+			defer api.Close()
+
+			c, _ := NewClient(api.URL, "guest", "guest")
+			_, err := c.ListExchangeBindingsWithSource("rabbit/hole", "")
+			Ω(err).Should(BeNil())
+			_, err = c.ListQueueBindingsBetween("rabbit/hole", "", "a.queue")
+			Ω(err).Should(BeNil())
+
+			rs := api.Requests()
+			Ω(rs).Should(HaveLen(2))
+			Ω(rs[0].Path).Should(Equal("/api/exchanges/rabbit%2Fhole/amq.default/bindings/source"))
+			Ω(rs[1].Path).Should(Equal("/api/bindings/rabbit%2Fhole/e/amq.default/q/a.queue"))
+		})
+	})
+
+	Context("GET /exchanges/{vhost}/{exchange}/bindings/source and destination", func() {
+		It("returns decoded response", func() {
+			vh := "rabbit/hole"
+			xn := "test.bindings.exchange.source"
+			qn := "test.bindings.exchange.source.queue"
+
+			_, err := rmqc.DeclareExchange(vh, xn, ExchangeSettings{Type: "topic"})
+			Ω(err).Should(BeNil())
+			_, err = rmqc.DeclareQueue(vh, qn, QueueSettings{})
+			Ω(err).Should(BeNil())
+
+			_, err = rmqc.DeclareBinding(vh, BindingInfo{
+				Source:          "amq.topic",
+				Destination:     xn,
+				DestinationType: "exchange",
+				RoutingKey:      "#",
+			})
+			Ω(err).Should(BeNil())
+			_, err = rmqc.DeclareBinding(vh, BindingInfo{
+				Source:          xn,
+				Destination:     qn,
+				DestinationType: "queue",
+				RoutingKey:      "a.key",
+			})
+			Ω(err).Should(BeNil())
+
+			awaitEventPropagation()
+			bs, err := rmqc.ListExchangeBindingsWithSource(vh, xn)
+			Ω(err).Should(BeNil())
+			Ω(bs).Should(HaveLen(1))
+			Ω(bs[0].Source).Should(Equal(xn))
+			Ω(bs[0].Destination).Should(Equal(qn))
+			Ω(bs[0].DestinationType).Should(Equal("queue"))
+			Ω(bs[0].RoutingKey).Should(Equal("a.key"))
+
+			bs, err = rmqc.ListExchangeBindingsWithDestination(vh, xn)
+			Ω(err).Should(BeNil())
+			Ω(bs).Should(HaveLen(1))
+			Ω(bs[0].Source).Should(Equal("amq.topic"))
+			Ω(bs[0].Destination).Should(Equal(xn))
+			Ω(bs[0].DestinationType).Should(Equal("exchange"))
+
+			rmqc.DeleteExchange(vh, xn)
+			rmqc.DeleteQueue(vh, qn)
+		})
+	})
+
+	Context("GET /bindings/{vhost}/e/{source}/q/{destination}", func() {
+		It("returns decoded response", func() {
+			vh := "rabbit/hole"
+			qn := "test.bindings.between.queue"
+
+			_, err := rmqc.DeclareQueue(vh, qn, QueueSettings{})
+			Ω(err).Should(BeNil())
+
+			for _, rk := range []string{"a.key", "another.key"} {
+				_, err = rmqc.DeclareBinding(vh, BindingInfo{
+					Source:          "amq.topic",
+					Destination:     qn,
+					DestinationType: "queue",
+					RoutingKey:      rk,
+				})
+				Ω(err).Should(BeNil())
+			}
+
+			awaitEventPropagation()
+			bs, err := rmqc.ListQueueBindingsBetween(vh, "amq.topic", qn)
+			Ω(err).Should(BeNil())
+			Ω(bs).Should(HaveLen(2))
+			for _, b := range bs {
+				Ω(b.Source).Should(Equal("amq.topic"))
+				Ω(b.Destination).Should(Equal(qn))
+			}
+
+			rmqc.DeleteQueue(vh, qn)
+		})
+	})
+
+	Context("GET /bindings/{vhost}/e/{source}/e/{destination}", func() {
+		It("returns decoded response", func() {
+			vh := "rabbit/hole"
+			xn := "test.bindings.between.exchange"
+
+			_, err := rmqc.DeclareExchange(vh, xn, ExchangeSettings{Type: "topic"})
+			Ω(err).Should(BeNil())
+
+			_, err = rmqc.DeclareBinding(vh, BindingInfo{
+				Source:          "amq.topic",
+				Destination:     xn,
+				DestinationType: "exchange",
+				RoutingKey:      "#",
+			})
+			Ω(err).Should(BeNil())
+
+			awaitEventPropagation()
+			bs, err := rmqc.ListExchangeBindingsBetween(vh, "amq.topic", xn)
+			Ω(err).Should(BeNil())
+			Ω(bs).Should(HaveLen(1))
+			Ω(bs[0].Source).Should(Equal("amq.topic"))
+			Ω(bs[0].Destination).Should(Equal(xn))
+			Ω(bs[0].DestinationType).Should(Equal("exchange"))
+			Ω(bs[0].RoutingKey).Should(Equal("#"))
+
+			rmqc.DeleteExchange(vh, xn)
+		})
+	})
+
 	Context("GET /permissions", func() {
 		It("returns decoded response", func() {
 			xs, err := rmqc.ListPermissions()
@@ -1187,53 +2048,266 @@ var _ = Describe("Rabbithole", func() {
 		})
 	})
 
-	Context("DELETE /queues/{vhost}/{queue}", func() {
-		It("deletes a queue", func() {
+	Context("PUT /queues/{vhost}/{queue} with a quorum queue type", func() {
+		It("declares a quorum queue", func() {
 			vh := "rabbit/hole"
-			qn := "temporary"
+			qn := "temporary.quorum"
 
-			_, err := rmqc.DeclareQueue(vh, qn, QueueSettings{Durable: false})
+			_, err := rmqc.DeclareQueue(vh, qn, QueueSettings{Type: QueueTypeQuorum, Durable: true})
 			Ω(err).Should(BeNil())
 
-			awaitEventPropagation()
-			rmqc.DeleteQueue(vh, qn)
 			awaitEventPropagation()
 			x, err := rmqc.GetQueue(vh, qn)
-			Ω(x).Should(BeNil())
-			Ω(err).Should(Equal(ErrorResponse{404, "Object Not Found", "Not Found"}))
+			Ω(err).Should(BeNil())
+			Ω(x.Type).Should(Equal(QueueTypeQuorum))
+			Ω(x.Arguments).Should(HaveKeyWithValue("x-queue-type", "quorum"))
+			Ω(x.Leader).Should(Equal(x.Node))
+			Ω(x.Members).Should(ConsistOf(x.Node))
+			Ω(x.OnlineMembers).Should(ConsistOf(x.Node))
+
+			rmqc.DeleteQueue(vh, qn)
 		})
 	})
 
-	Context("DELETE /queues/{vhost}/{queue}/contents", func() {
-		It("purges a queue", func() {
+	Context("quorum queue replicas", func() {
+		It("rejects adding a replica on a node that already has one", func() {
 			vh := "rabbit/hole"
-			qn := "temporary"
+			qn := "temporary.quorum.replicas"
 
-			_, err := rmqc.DeclareQueue(vh, qn, QueueSettings{Durable: false})
+			_, err := rmqc.DeclareQueue(vh, qn, QueueSettings{Type: QueueTypeQuorum, Durable: true})
 			Ω(err).Should(BeNil())
 
 			awaitEventPropagation()
-			_, err = rmqc.PurgeQueue(vh, qn)
-			awaitEventPropagation()
+			x, err := rmqc.GetQueue(vh, qn)
+			Ω(err).Should(BeNil())
+
+			res, err := rmqc.AddQuorumQueueReplica(vh, qn, x.Leader)
 			Ω(err).Should(BeNil())
+			Ω(res.StatusCode).Should(BeNumerically(">=", http.StatusBadRequest))
+
+			res, err = rmqc.DeleteQuorumQueueReplica(vh, qn, "rabbit@no-such-node")
+			Ω(err).Should(BeNil())
+			Ω(res.StatusCode).Should(BeNumerically(">=", http.StatusBadRequest))
+
 			rmqc.DeleteQueue(vh, qn)
-			awaitEventPropagation()
-			x, err := rmqc.GetQueue(vh, qn)
-			awaitEventPropagation()
-			Ω(x).Should(BeNil())
-			Ω(err).Should(Equal(ErrorResponse{404, "Object Not Found", "Not Found"}))
 		})
-	})
 
-	Context("GET /policies", func() {
-		Context("when policy exists", func() {
-			It("returns decoded response", func() {
-				policy1 := Policy{
-					Pattern:    "abc",
-					ApplyTo:    "all",
-					Definition: PolicyDefinition{"expires": 100, "ha-mode": "all"},
-					Priority:   0,
-				}
+		It("grows quorum queues onto a node", func() {
+			xs, err := rmqc.ListNodes()
+			Ω(err).Should(BeNil())
+
+			res, err := rmqc.GrowQuorumQueueReplicas(xs[0].Name, QuorumQueueGrowSettings{
+				VhostPattern: "^rabbit/hole$",
+				QueuePattern: "^no-such-queue$",
+				Strategy:     GrowAll,
+			})
+			Ω(err).Should(BeNil())
+			Ω(res.StatusCode).Should(BeNumerically("<", http.StatusBadRequest))
+		})
+	})
+
+	Context("DeclareStream", func() {
+		It("declares a stream", func() {
+			vh := "rabbit/hole"
+			sn := "temporary.stream"
+
+			_, err := rmqc.DeclareStream(vh, sn, StreamSettings{
+				MaxLengthBytes:      20000000,
+				MaxAge:              "7D",
+				MaxSegmentSizeBytes: 500000,
+				InitialClusterSize:  1,
+			})
+			Ω(err).Should(BeNil())
+
+			awaitEventPropagation()
+			x, err := rmqc.GetQueue(vh, sn)
+			Ω(err).Should(BeNil())
+			Ω(x.Type).Should(Equal(QueueTypeStream))
+			Ω(x.Durable).Should(BeTrue())
+			Ω(x.Arguments).Should(HaveKeyWithValue("x-max-length-bytes", BeNumerically("==", 20000000)))
+			Ω(x.Arguments).Should(HaveKeyWithValue("x-max-age", "7D"))
+			Ω(x.Arguments).Should(HaveKeyWithValue("x-stream-max-segment-size-bytes", BeNumerically("==", 500000)))
+			Ω(x.Arguments).Should(HaveKeyWithValue("x-initial-cluster-size", BeNumerically("==", 1)))
+			Ω(x.Leader).ShouldNot(BeEmpty())
+
+			rmqc.DeleteQueue(vh, sn)
+		})
+
+		It("validates settings", func() {
+			Ω(StreamSettings{MaxAge: "7 days"}.Validate()).ShouldNot(Succeed())
+			Ω(StreamSettings{MaxLengthBytes: -1}.Validate()).ShouldNot(Succeed())
+			Ω(StreamSettings{MaxAge: "12h"}.Validate()).Should(Succeed())
+
+			_, err := rmqc.DeclareStream("rabbit/hole", "temporary.stream", StreamSettings{MaxAge: "forever"})
+			Ω(err).Should(HaveOccurred())
+		})
+	})
+
+	Context("GET /stream/*", func() {
+		It("returns decoded responses", func() {
+			conns, err := rmqc.ListStreamConnections()
+			Ω(err).Should(BeNil())
+			Ω(conns).Should(BeEmpty())
+
+			conns, err = rmqc.ListStreamConnectionsIn("rabbit/hole")
+			Ω(err).Should(BeNil())
+			Ω(conns).Should(BeEmpty())
+
+			ps, err := rmqc.ListStreamPublishers()
+			Ω(err).Should(BeNil())
+			Ω(ps).Should(BeEmpty())
+
+			ps, err = rmqc.ListStreamPublishersIn("rabbit/hole")
+			Ω(err).Should(BeNil())
+			Ω(ps).Should(BeEmpty())
+
+			cs, err := rmqc.ListStreamConsumers()
+			Ω(err).Should(BeNil())
+			Ω(cs).Should(BeEmpty())
+
+			cs, err = rmqc.ListStreamConsumersIn("rabbit/hole")
+			Ω(err).Should(BeNil())
+			Ω(cs).Should(BeEmpty())
+		})
+	})
+
+	Context("QueueArguments", func() {
+		It("builds arguments with the expected types", func() {
+			args, err := NewQueueArguments().
+				MessageTTL(5 * time.Second).
+				Expires(time.Minute).
+				MaxLength(1000).
+				MaxLengthBytes(1 << 20).
+				Overflow(OverflowRejectPublishDLX).
+				DeadLetterExchange("dlx").
+				DeadLetterRoutingKey("dead").
+				MaxPriority(10).
+				QueueMode(QueueModeLazy).
+				SingleActiveConsumer(true).
+				QueueType(QueueTypeClassic).
+				Build()
+			Ω(err).Should(BeNil())
+			Ω(args).Should(Equal(map[string]interface{}{
+				"x-message-ttl":             int64(5000),
+				"x-expires":                 int64(60000),
+				"x-max-length":              int64(1000),
+				"x-max-length-bytes":        int64(1 << 20),
+				"x-overflow":                "reject-publish-dlx",
+				"x-dead-letter-exchange":    "dlx",
+				"x-dead-letter-routing-key": "dead",
+				"x-max-priority":            10,
+				"x-queue-mode":              "lazy",
+				"x-single-active-consumer":  true,
+				"x-queue-type":              "classic",
+			}))
+		})
+
+		It("rejects invalid values", func() {
+			_, err := NewQueueArguments().MessageTTL(-time.Second).Build()
+			Ω(err).Should(HaveOccurred())
+
+			_, err = NewQueueArguments().Expires(0).Build()
+			Ω(err).Should(HaveOccurred())
+
+			_, err = NewQueueArguments().MaxLength(-1).Build()
+			Ω(err).Should(HaveOccurred())
+
+			_, err = NewQueueArguments().Overflow("drop-tail").Build()
+			Ω(err).Should(HaveOccurred())
+
+			_, err = NewQueueArguments().MaxPriority(256).Build()
+			Ω(err).Should(HaveOccurred())
+
+			_, err = NewQueueArguments().QueueMode("eager").Build()
+			Ω(err).Should(HaveOccurred())
+
+			_, err = NewQueueArguments().QueueType("temporary").Build()
+			Ω(err).Should(HaveOccurred())
+		})
+
+		It("rejects invalid combinations", func() {
+			_, err := NewQueueArguments().DeadLetterRoutingKey("dead").Build()
+			Ω(err).Should(HaveOccurred())
+
+			_, err = NewQueueArguments().QueueType(QueueTypeQuorum).MaxPriority(10).Build()
+			Ω(err).Should(HaveOccurred())
+
+			_, err = NewQueueArguments().QueueType(QueueTypeQuorum).QueueMode(QueueModeLazy).Build()
+			Ω(err).Should(HaveOccurred())
+		})
+
+		It("declares a queue with built arguments", func() {
+			vh := "rabbit/hole"
+			qn := "temporary.arguments"
+
+			args, err := NewQueueArguments().
+				MessageTTL(5 * time.Second).
+				MaxLength(1000).
+				DeadLetterExchange("amq.fanout").
+				Build()
+			Ω(err).Should(BeNil())
+
+			_, err = rmqc.DeclareQueue(vh, qn, QueueSettings{Arguments: args})
+			Ω(err).Should(BeNil())
+
+			awaitEventPropagation()
+			x, err := rmqc.GetQueue(vh, qn)
+			Ω(err).Should(BeNil())
+			Ω(x.Arguments).Should(HaveKeyWithValue("x-message-ttl", BeNumerically("==", 5000)))
+			Ω(x.Arguments).Should(HaveKeyWithValue("x-max-length", BeNumerically("==", 1000)))
+			Ω(x.Arguments).Should(HaveKeyWithValue("x-dead-letter-exchange", "amq.fanout"))
+
+			rmqc.DeleteQueue(vh, qn)
+		})
+	})
+
+	Context("DELETE /queues/{vhost}/{queue}", func() {
+		It("deletes a queue", func() {
+			vh := "rabbit/hole"
+			qn := "temporary"
+
+			_, err := rmqc.DeclareQueue(vh, qn, QueueSettings{Durable: false})
+			Ω(err).Should(BeNil())
+
+			awaitEventPropagation()
+			rmqc.DeleteQueue(vh, qn)
+			awaitEventPropagation()
+			x, err := rmqc.GetQueue(vh, qn)
+			Ω(x).Should(BeNil())
+			Ω(err).Should(Equal(ErrorResponse{404, "Object Not Found", "Not Found"}))
+		})
+	})
+
+	Context("DELETE /queues/{vhost}/{queue}/contents", func() {
+		It("purges a queue", func() {
+			vh := "rabbit/hole"
+			qn := "temporary"
+
+			_, err := rmqc.DeclareQueue(vh, qn, QueueSettings{Durable: false})
+			Ω(err).Should(BeNil())
+
+			awaitEventPropagation()
+			_, err = rmqc.PurgeQueue(vh, qn)
+			awaitEventPropagation()
+			Ω(err).Should(BeNil())
+			rmqc.DeleteQueue(vh, qn)
+			awaitEventPropagation()
+			x, err := rmqc.GetQueue(vh, qn)
+			awaitEventPropagation()
+			Ω(x).Should(BeNil())
+			Ω(err).Should(Equal(ErrorResponse{404, "Object Not Found", "Not Found"}))
+		})
+	})
+
+	Context("GET /policies", func() {
+		Context("when policy exists", func() {
+			It("returns decoded response", func() {
+				policy1 := Policy{
+					Pattern:    "abc",
+					ApplyTo:    "all",
+					Definition: PolicyDefinition{"expires": 100, "ha-mode": "all"},
+					Priority:   0,
+				}
 
 				policy2 := Policy{
 					Pattern:    ".*",
@@ -1458,4 +2532,1240 @@ var _ = Describe("Rabbithole", func() {
 			Ω(err).Should(BeNil())
 		})
 	})
+
+	Context("federation upstreams and links", func() {
+		It("lists upstreams and reports link status", func() {
+			vh := "rabbit/hole"
+			un := "temporary.upstream"
+			xn := "federated.exchange"
+
+			_, err := rmqc.PutFederationUpstream(vh, un, FederationDefinition{
+				Uri: URISet{"amqp://127.0.0.1/%2f"},
+			})
+			Ω(err).Should(BeNil())
+
+			_, err = rmqc.PutPolicy(vh, "federation", Policy{
+				Pattern:    "^federated\\.",
+				ApplyTo:    "exchanges",
+				Definition: PolicyDefinition{"federation-upstream": un},
+			})
+			Ω(err).Should(BeNil())
+
+			_, err = rmqc.DeclareExchange(vh, xn, ExchangeSettings{Type: "topic"})
+			Ω(err).Should(BeNil())
+
+			awaitEventPropagation()
+			up, err := rmqc.GetFederationUpstream(vh, un)
+			Ω(err).Should(BeNil())
+			Ω(up.Name).Should(Equal(un))
+			Ω(up.Vhost).Should(Equal(vh))
+			Ω(up.Component).Should(Equal("federation-upstream"))
+			Ω(up.Definition.Uri).Should(Equal(URISet{"amqp://127.0.0.1/%2f"}))
+
+			ups, err := rmqc.ListFederationUpstreamsIn(vh)
+			Ω(err).Should(BeNil())
+			Ω(ups).Should(HaveLen(1))
+
+			ups, err = rmqc.ListFederationUpstreams()
+			Ω(err).Should(BeNil())
+			Ω(ups).ShouldNot(BeEmpty())
+
+			links, err := rmqc.ListFederationLinksIn(vh)
+			Ω(err).Should(BeNil())
+			Ω(links).Should(HaveLen(1))
+
+			link := links[0]
+			Ω(link.Upstream).Should(Equal(un))
+			Ω(link.Type).Should(Equal("exchange"))
+			Ω(link.Exchange).Should(Equal(xn))
+			Ω(link.UpstreamExchange).Should(Equal(xn))
+			Ω(link.Status).Should(BeElementOf(FederationLinkStarting, FederationLinkRunning))
+
+			links, err = rmqc.ListFederationLinks()
+			Ω(err).Should(BeNil())
+			Ω(links).ShouldNot(BeEmpty())
+
+			rmqc.DeleteExchange(vh, xn)
+			rmqc.DeletePolicy(vh, "federation")
+			rmqc.DeleteFederationUpstream(vh, un)
+		})
+	})
+
+	Context("PUT /parameters/federation-upstream/{vhost}/{name} with a complete definition", func() {
+		It("declares an upstream", func() {
+			vh := "rabbit/hole"
+			un := "temporary.upstream.complete"
+
+			expires := 60000
+			def := FederationDefinition{
+				Uri:         URISet{"amqp://127.0.0.1/%2f", "amqp://localhost/%2f"},
+				Expires:     &expires,
+				MaxHops:     2,
+				AckMode:     "on-publish",
+				ConsumerTag: "federation.consumer",
+				BindNoWait:  true,
+			}
+			_, err := rmqc.PutFederationUpstream(vh, un, def)
+			Ω(err).Should(BeNil())
+
+			awaitEventPropagation()
+			up, err := rmqc.GetFederationUpstream(vh, un)
+			Ω(err).Should(BeNil())
+			Ω(up.Definition.Uri).Should(Equal(def.Uri))
+			Ω(*up.Definition.Expires).Should(Equal(expires))
+			Ω(up.Definition.MessageTTL).Should(BeNil())
+			Ω(up.Definition.MaxHops).Should(Equal(2))
+			Ω(up.Definition.ConsumerTag).Should(Equal("federation.consumer"))
+			Ω(up.Definition.BindNoWait).Should(BeTrue())
+
+			rmqc.DeleteFederationUpstream(vh, un)
+		})
+	})
+
+	Context("federation upstream sets", func() {
+		It("declares, lists and deletes upstream sets", func() {
+			vh := "rabbit/hole"
+			sn := "temporary.upstream.set"
+
+			for _, un := range []string{"temporary.upstream.a", "temporary.upstream.b"} {
+				_, err := rmqc.PutFederationUpstream(vh, un, FederationDefinition{
+					Uri: URISet{"amqp://127.0.0.1/%2f"},
+				})
+				Ω(err).Should(BeNil())
+			}
+
+			members := []FederationUpstreamSetMember{
+				{Upstream: "temporary.upstream.a"},
+				{Upstream: "temporary.upstream.b", Exchange: "another.exchange"},
+			}
+			_, err := rmqc.PutFederationUpstreamSet(vh, sn, members)
+			Ω(err).Should(BeNil())
+
+			awaitEventPropagation()
+			set, err := rmqc.GetFederationUpstreamSet(vh, sn)
+			Ω(err).Should(BeNil())
+			Ω(set.Name).Should(Equal(sn))
+			Ω(set.Vhost).Should(Equal(vh))
+			Ω(set.Component).Should(Equal("federation-upstream-set"))
+			Ω(set.Definition).Should(Equal(members))
+
+			sets, err := rmqc.ListFederationUpstreamSetsIn(vh)
+			Ω(err).Should(BeNil())
+			Ω(sets).Should(HaveLen(1))
+
+			sets, err = rmqc.ListFederationUpstreamSets()
+			Ω(err).Should(BeNil())
+			Ω(sets).ShouldNot(BeEmpty())
+
+			_, err = rmqc.DeleteFederationUpstreamSet(vh, sn)
+			Ω(err).Should(BeNil())
+
+			awaitEventPropagation()
+			_, err = rmqc.GetFederationUpstreamSet(vh, sn)
+			Ω(err).Should(HaveOccurred())
+
+			rmqc.DeleteFederationUpstream(vh, "temporary.upstream.a")
+			rmqc.DeleteFederationUpstream(vh, "temporary.upstream.b")
+		})
+	})
+
+	Context("PolicyDefinitionBuilder", func() {
+		It("builds definitions with the expected types", func() {
+			def, err := NewPolicyDefinition().
+				HaMode(HaModeExactly).
+				HaParamsCount(2).
+				HaSyncMode(HaSyncModeAutomatic).
+				FederationUpstreamSet("all").
+				MessageTTL(time.Minute).
+				Expires(time.Hour).
+				MaxLength(100).
+				Overflow(OverflowRejectPublish).
+				DeadLetterExchange("dlx").
+				QueueMode(QueueModeLazy).
+				DeliveryLimit(5).
+				QueueMasterLocator(QueueMasterLocatorMinMasters).
+				Build()
+			Ω(err).Should(BeNil())
+			Ω(def).Should(Equal(PolicyDefinition{
+				"ha-mode":                 "exactly",
+				"ha-params":               2,
+				"ha-sync-mode":            "automatic",
+				"federation-upstream-set": "all",
+				"message-ttl":             int64(60000),
+				"expires":                 int64(3600000),
+				"max-length":              int64(100),
+				"overflow":                "reject-publish",
+				"dead-letter-exchange":    "dlx",
+				"queue-mode":              "lazy",
+				"delivery-limit":          5,
+				"queue-master-locator":    "min-masters",
+			}))
+
+			def, err = NewPolicyDefinition().HaMode(HaModeNodes).HaParamsNodes(NodeNames{"rabbit@a", "rabbit@b"}).Build()
+			Ω(err).Should(BeNil())
+			Ω(def["ha-params"]).Should(Equal(NodeNames{"rabbit@a", "rabbit@b"}))
+		})
+
+		It("rejects invalid combinations", func() {
+			_, err := NewPolicyDefinition().HaMode(HaModeExactly).Build()
+			Ω(err).Should(HaveOccurred())
+
+			_, err = NewPolicyDefinition().HaMode(HaModeNodes).Build()
+			Ω(err).Should(HaveOccurred())
+
+			_, err = NewPolicyDefinition().HaMode(HaModeNodes).HaParamsCount(2).Build()
+			Ω(err).Should(HaveOccurred())
+
+			_, err = NewPolicyDefinition().HaMode(HaModeAll).HaParamsCount(2).Build()
+			Ω(err).Should(HaveOccurred())
+
+			_, err = NewPolicyDefinition().HaParamsCount(2).Build()
+			Ω(err).Should(HaveOccurred())
+
+			_, err = NewPolicyDefinition().HaSyncMode(HaSyncModeManual).Build()
+			Ω(err).Should(HaveOccurred())
+
+			_, err = NewPolicyDefinition().FederationUpstream("up").FederationUpstreamSet("all").Build()
+			Ω(err).Should(HaveOccurred())
+		})
+
+		It("rejects invalid values", func() {
+			_, err := NewPolicyDefinition().HaMode("some").Build()
+			Ω(err).Should(HaveOccurred())
+
+			_, err = NewPolicyDefinition().MessageTTL(-time.Second).Build()
+			Ω(err).Should(HaveOccurred())
+
+			_, err = NewPolicyDefinition().MaxLength(-1).Build()
+			Ω(err).Should(HaveOccurred())
+
+			_, err = NewPolicyDefinition().Overflow("drop-tail").Build()
+			Ω(err).Should(HaveOccurred())
+
+			_, err = NewPolicyDefinition().QueueMasterLocator("nearest").Build()
+			Ω(err).Should(HaveOccurred())
+		})
+
+		It("validates definitions decoded from JSON", func() {
+			var def PolicyDefinition
+			err := json.Unmarshal([]byte(`{"ha-mode":"exactly","ha-params":2,"message-ttl":1000}`), &def)
+			Ω(err).Should(BeNil())
+			Ω(def.Validate()).Should(Succeed())
+
+			err = json.Unmarshal([]byte(`{"ha-mode":"nodes","ha-params":["rabbit@a"]}`), &def)
+			Ω(err).Should(BeNil())
+			Ω(def.Validate()).Should(Succeed())
+		})
+
+		It("validates definitions before putting policies", func() {
+			api := fakeapi.New()
+			defer api.Close()
+
+			c, _ := NewClient(api.URL, "guest", "guest")
+			_, err := c.PutPolicy("/", "invalid", Policy{
+				Pattern:    ".*",
+				Definition: PolicyDefinition{"ha-mode": "exactly"},
+			})
+			Ω(err).Should(MatchError(`ha-mode "exactly" requires ha-params to be a positive number of mirrors`))
+			Ω(api.Requests()).Should(BeEmpty())
+		})
+
+		It("creates a policy", func() {
+			def, err := NewPolicyDefinition().MaxLength(100).Overflow(OverflowRejectPublish).Build()
+			Ω(err).Should(BeNil())
+
+			_, err = rmqc.PutPolicy("rabbit/hole", "built", Policy{
+				Pattern:    "^built\\.",
+				ApplyTo:    "queues",
+				Definition: def,
+			})
+			Ω(err).Should(BeNil())
+
+			awaitEventPropagation()
+			pol, err := rmqc.GetPolicy("rabbit/hole", "built")
+			Ω(err).Should(BeNil())
+			Ω(pol.Definition["max-length"]).Should(BeNumerically("==", 100))
+			Ω(pol.Definition["overflow"]).Should(Equal("reject-publish"))
+
+			_, err = rmqc.DeletePolicy("rabbit/hole", "built")
+			Ω(err).Should(BeNil())
+		})
+	})
+
+	Context("EvaluatePolicies", func() {
+		policies := []Policy{
+			{Vhost: "/", Name: "all-low", Pattern: ".*", ApplyTo: "all", Priority: 0},
+			{Vhost: "/", Name: "queues-high", Pattern: "^orders\\.", ApplyTo: "queues", Priority: 10},
+			{Vhost: "/", Name: "exchanges-high", Pattern: "^orders\\.", ApplyTo: "exchanges", Priority: 10},
+			{Vhost: "/", Name: "quorum-only", Pattern: "^orders\\.", ApplyTo: "quorum_queues", Priority: 20},
+			{Vhost: "/", Name: "b-tie", Pattern: "audit", Priority: 5},
+			{Vhost: "/", Name: "a-tie", Pattern: "audit", Priority: 5},
+			{Vhost: "other", Name: "other-vhost", Pattern: ".*", Priority: 100},
+		}
+
+		It("applies the matching policy with the highest priority", func() {
+			m, err := EvaluatePolicies(policies, PolicyTarget{Vhost: "/", Name: "orders.eu", Kind: PolicyTargetQueue})
+			Ω(err).Should(BeNil())
+			Ω(m.Effective.Name).Should(Equal("queues-high"))
+			Ω(m.Shadowed).Should(HaveLen(1))
+			Ω(m.Shadowed[0].Name).Should(Equal("all-low"))
+		})
+
+		It("takes the kind and queue type into account", func() {
+			m, err := EvaluatePolicies(policies, PolicyTarget{Vhost: "/", Name: "orders.eu", Kind: PolicyTargetExchange})
+			Ω(err).Should(BeNil())
+			Ω(m.Effective.Name).Should(Equal("exchanges-high"))
+
+			m, err = EvaluatePolicies(policies, PolicyTarget{Vhost: "/", Name: "orders.eu", Kind: PolicyTargetQueue, QueueType: QueueTypeQuorum})
+			Ω(err).Should(BeNil())
+			Ω(m.Effective.Name).Should(Equal("quorum-only"))
+			Ω(m.Shadowed).Should(HaveLen(2))
+			Ω(m.Shadowed[0].Name).Should(Equal("queues-high"))
+		})
+
+		It("orders policies with the same priority by name", func() {
+			m, err := EvaluatePolicies(policies, PolicyTarget{Vhost: "/", Name: "an.audit.log", Kind: PolicyTargetQueue})
+			Ω(err).Should(BeNil())
+			Ω(m.Effective.Name).Should(Equal("a-tie"))
+			Ω(m.Shadowed[0].Name).Should(Equal("b-tie"))
+			Ω(m.Shadowed[1].Name).Should(Equal("all-low"))
+		})
+
+		It("returns no match when no policy matches", func() {
+			m, err := EvaluatePolicies(policies[1:4], PolicyTarget{Vhost: "/", Name: "payments", Kind: PolicyTargetQueue})
+			Ω(err).Should(BeNil())
+			Ω(m.Effective).Should(BeNil())
+			Ω(m.Shadowed).Should(BeEmpty())
+		})
+
+		It("reports invalid patterns", func() {
+			_, err := EvaluatePolicies([]Policy{{Name: "broken", Pattern: "(", ApplyTo: "all"}}, PolicyTarget{Name: "q", Kind: PolicyTargetQueue})
+			Ω(err).Should(HaveOccurred())
+		})
+
+		It("agrees with the policy applied by RabbitMQ", func() {
+			vh := "rabbit/hole"
+			qn := "evaluated.queue"
+
+			_, err := rmqc.PutPolicy(vh, "evaluated-low", Policy{Pattern: "^evaluated\\.", ApplyTo: "queues", Priority: 1, Definition: PolicyDefinition{"max-length": 10}})
+			Ω(err).Should(BeNil())
+			_, err = rmqc.PutPolicy(vh, "evaluated-high", Policy{Pattern: "queue$", ApplyTo: "all", Priority: 2, Definition: PolicyDefinition{"max-length": 20}})
+			Ω(err).Should(BeNil())
+			_, err = rmqc.DeclareQueue(vh, qn, QueueSettings{})
+			Ω(err).Should(BeNil())
+
+			awaitEventPropagation()
+			ps, err := rmqc.ListPoliciesIn(vh)
+			Ω(err).Should(BeNil())
+			m, err := EvaluatePolicies(ps, PolicyTarget{Vhost: vh, Name: qn, Kind: PolicyTargetQueue})
+			Ω(err).Should(BeNil())
+
+			q, err := rmqc.GetQueue(vh, qn)
+			Ω(err).Should(BeNil())
+			Ω(m.Effective.Name).Should(Equal(q.Policy))
+			Ω(m.Shadowed).Should(HaveLen(1))
+
+			rmqc.DeleteQueue(vh, qn)
+			rmqc.DeletePolicy(vh, "evaluated-low")
+			rmqc.DeletePolicy(vh, "evaluated-high")
+		})
+	})
+
+	Context("PermissionEvaluator", func() {
+		perms := []PermissionInfo{
+			{User: "app", Vhost: "/", Configure: "^app\\.", Write: "^(app\\.|amq\\.default$|events$)", Read: "^app\\.|^events$"},
+			{User: "app", Vhost: "locked", Configure: "", Write: "", Read: ""},
+			{User: "other", Vhost: "/", Configure: ".*", Write: ".*", Read: ".*"},
+		}
+		topicPerms := []TopicPermissionInfo{
+			{User: "app", Vhost: "/", Exchange: "events", Write: "^{username}\\.", Read: "^app\\.|^shared\\."},
+		}
+
+		It("checks configure permissions when declaring queues", func() {
+			e, err := NewPermissionEvaluator("app", perms, topicPerms)
+			Ω(err).Should(BeNil())
+
+			d, err := e.Evaluate(PermissionRequest{Vhost: "/", Operation: OpDeclareQueue, Queue: "app.orders"})
+			Ω(err).Should(BeNil())
+			Ω(d.Allowed).Should(BeTrue())
+
+			d, err = e.Evaluate(PermissionRequest{Vhost: "/", Operation: OpDeclareQueue, Queue: "orders"})
+			Ω(err).Should(BeNil())
+			Ω(d.Allowed).Should(BeFalse())
+			Ω(d.Checks[0].Pattern).Should(Equal("^app\\."))
+
+			// server-named queues are named amq.gen-*
+			d, err = e.Evaluate(PermissionRequest{Vhost: "/", Operation: OpDeclareQueue})
+			Ω(err).Should(BeNil())
+			Ω(d.Allowed).Should(BeFalse())
+
+			d, err = e.Evaluate(PermissionRequest{Vhost: "/", Operation: OpDeclareQueue, Queue: "amq.app"})
+			Ω(err).Should(BeNil())
+			Ω(d.Allowed).Should(BeFalse())
+			Ω(d.Reason).ShouldNot(BeEmpty())
+		})
+
+		It("treats the default exchange as amq.default", func() {
+			e, err := NewPermissionEvaluator("app", perms, topicPerms)
+			Ω(err).Should(BeNil())
+
+			d, err := e.Evaluate(PermissionRequest{Vhost: "/", Operation: OpPublish})
+			Ω(err).Should(BeNil())
+			Ω(d.Allowed).Should(BeTrue())
+			Ω(d.Checks[0].Name).Should(Equal("amq.default"))
+		})
+
+		It("denies everything for empty patterns and unknown virtual hosts", func() {
+			e, err := NewPermissionEvaluator("app", perms, topicPerms)
+			Ω(err).Should(BeNil())
+
+			for _, vh := range []string{"locked", "unknown"} {
+				d, err := e.Evaluate(PermissionRequest{Vhost: vh, Operation: OpConsume, Queue: "app.orders"})
+				Ω(err).Should(BeNil())
+				Ω(d.Allowed).Should(BeFalse())
+			}
+		})
+
+		It("checks write on the queue and read on the exchange when binding", func() {
+			e, err := NewPermissionEvaluator("app", perms, topicPerms)
+			Ω(err).Should(BeNil())
+
+			d, err := e.Evaluate(PermissionRequest{Vhost: "/", Operation: OpBindQueue, Queue: "app.orders", Exchange: "events", ExchangeType: "topic", RoutingKey: "shared.orders"})
+			Ω(err).Should(BeNil())
+			Ω(d.Allowed).Should(BeTrue())
+			Ω(d.Checks).Should(HaveLen(3))
+
+			d, err = e.Evaluate(PermissionRequest{Vhost: "/", Operation: OpBindQueue, Queue: "app.orders", Exchange: "events", ExchangeType: "topic", RoutingKey: "private.orders"})
+			Ω(err).Should(BeNil())
+			Ω(d.Allowed).Should(BeFalse())
+			Ω(d.Checks[2].Kind).Should(Equal(PermissionResourceTopic))
+		})
+
+		It("applies topic permissions to published routing keys", func() {
+			e, err := NewPermissionEvaluator("app", perms, topicPerms)
+			Ω(err).Should(BeNil())
+
+			d, err := e.Evaluate(PermissionRequest{Vhost: "/", Operation: OpPublish, Exchange: "events", ExchangeType: "topic", RoutingKey: "app.created"})
+			Ω(err).Should(BeNil())
+			Ω(d.Allowed).Should(BeTrue())
+
+			d, err = e.Evaluate(PermissionRequest{Vhost: "/", Operation: OpPublish, Exchange: "events", ExchangeType: "topic", RoutingKey: "other.created"})
+			Ω(err).Should(BeNil())
+			Ω(d.Allowed).Should(BeFalse())
+
+			// topic permissions only apply to topic exchanges
+			d, err = e.Evaluate(PermissionRequest{Vhost: "/", Operation: OpPublish, Exchange: "events", ExchangeType: "direct", RoutingKey: "other.created"})
+			Ω(err).Should(BeNil())
+			Ω(d.Allowed).Should(BeTrue())
+		})
+
+		It("reports the resources a user can access", func() {
+			e, err := NewPermissionEvaluator("app", perms, topicPerms)
+			Ω(err).Should(BeNil())
+
+			qs := []QueueInfo{{Vhost: "/", Name: "app.b"}, {Vhost: "/", Name: "app.a"}, {Vhost: "/", Name: "billing"}, {Vhost: "locked", Name: "app.a"}}
+			xs := []ExchangeInfo{{Vhost: "/", Name: ""}, {Vhost: "/", Name: "amq.topic"}}
+
+			r := e.Report(qs, xs)
+			Ω(r).Should(HaveLen(3))
+			Ω(r[0]).Should(Equal(ResourceAccess{Vhost: "/", Kind: PermissionResourceExchange, Name: "amq.default", Write: true}))
+			Ω(r[1].Name).Should(Equal("app.a"))
+			Ω(r[1].Configure && r[1].Write && r[1].Read).Should(BeTrue())
+			Ω(r[2].Name).Should(Equal("app.b"))
+		})
+
+		It("returns an error for invalid patterns", func() {
+			_, err := NewPermissionEvaluator("app", []PermissionInfo{{User: "app", Vhost: "/", Configure: "(", Write: ".*", Read: ".*"}}, nil)
+			Ω(err).ShouldNot(BeNil())
+		})
+	})
+
+	Context("bearer token authentication", func() {
+		// stands in for an OAuth 2 authorization server
+		newTokenIssuer := func(expiresIn int) (*fakeapi.Server, string) {
+			var issued int32
+			issuer := fakeapi.New()
+			issuer.Handle("/oauth/token", func(w http.ResponseWriter, r *http.Request) {
+				defer GinkgoRecover()
+				Ω(r.Method).Should(Equal("POST"))
+				Ω(r.ParseForm()).Should(Succeed())
+				Ω(r.PostForm.Get("grant_type")).Should(Equal("client_credentials"))
+				if r.PostForm.Get("client_secret") != "s3krE7" {
+					fakeapi.JSON(http.StatusUnauthorized, `{"error":"invalid_client","error_description":"Bad credentials"}`)(w, r)
+					return
+				}
+				n := atomic.AddInt32(&issued, 1)
+				fmt.Fprintf(w, `{"access_token":"token-%d","token_type":"bearer","expires_in":%d}`, n, expiresIn)
+			})
+			return issuer, issuer.URL + "/oauth/token"
+		}
+
+		var api *fakeapi.Server
+
+		BeforeEach(func() {
+			api = fakeapi.New().Respond("/api/vhosts", http.StatusOK, `[]`)
+		})
+
+		AfterEach(func() {
+			api.Close()
+		})
+
+		It("sends a static token", func() {
+			c, _ := NewClient(api.URL, "guest", "guest")
+			c.SetTokenSource(StaticTokenSource("a.token"))
+			_, err := c.ListVhosts()
+			Ω(err).Should(BeNil())
+			Ω(authorizationHeaders(api)).Should(Equal([]string{"Bearer a.token"}))
+		})
+
+		It("reuses tokens until they are about to expire", func() {
+			issuer, tokenURL := newTokenIssuer(3600)
+			defer issuer.Close()
+
+			c, _ := NewClient(api.URL, "", "")
+			c.SetTokenSource(&ClientCredentialsTokenSource{TokenURL: tokenURL, ClientID: "rabbithole", ClientSecret: "s3krE7", Scopes: []string{"rabbitmq.tag:management"}})
+			_, err := c.ListVhosts()
+			Ω(err).Should(BeNil())
+			_, err = c.ListVhosts()
+			Ω(err).Should(BeNil())
+
+			Ω(issuer.Requests()).Should(HaveLen(1))
+			Ω(authorizationHeaders(api)).Should(Equal([]string{"Bearer token-1", "Bearer token-1"}))
+		})
+
+		It("refreshes expiring tokens", func() {
+			issuer, tokenURL := newTokenIssuer(1)
+			defer issuer.Close()
+
+			c, _ := NewClient(api.URL, "", "")
+			c.SetTokenSource(&ClientCredentialsTokenSource{TokenURL: tokenURL, ClientID: "rabbithole", ClientSecret: "s3krE7"})
+			_, err := c.ListVhosts()
+			Ω(err).Should(BeNil())
+			_, err = c.ListVhosts()
+			Ω(err).Should(BeNil())
+
+			Ω(issuer.Requests()).Should(HaveLen(2))
+			Ω(authorizationHeaders(api)).Should(Equal([]string{"Bearer token-1", "Bearer token-2"}))
+		})
+
+		It("returns token endpoint errors", func() {
+			issuer, tokenURL := newTokenIssuer(3600)
+			defer issuer.Close()
+
+			c, _ := NewClient(api.URL, "", "")
+			c.SetTokenSource(&ClientCredentialsTokenSource{TokenURL: tokenURL, ClientID: "rabbithole", ClientSecret: "wrong"})
+			_, err := c.ListVhosts()
+			Ω(err).Should(HaveOccurred())
+			Ω(err.Error()).Should(ContainSubstring("invalid_client"))
+			Ω(api.Requests()).Should(BeEmpty())
+		})
+	})
+
+	Context("credentials providers", func() {
+		var api *fakeapi.Server
+
+		// makes the API accept a single set of credentials
+		accept := func(username, password string) {
+			api.HandleOthers(func(w http.ResponseWriter, r *http.Request) {
+				if u, p, _ := r.BasicAuth(); u != username || p != password {
+					fakeapi.JSON(http.StatusUnauthorized, `{"error":"not_authorised","reason":"Login failed"}`)(w, r)
+					return
+				}
+				w.WriteHeader(http.StatusNoContent)
+			})
+		}
+
+		BeforeEach(func() {
+			api = fakeapi.New()
+		})
+
+		AfterEach(func() {
+			api.Close()
+		})
+
+		It("uses static credentials", func() {
+			accept("svc", "s3krE7")
+
+			c, _ := NewClient(api.URL, "guest", "guest")
+			c.SetCredentialsProvider(NewStaticCredentialsProvider("svc", "s3krE7"))
+			resp, err := c.PutVhost("rabbit/hole", VhostSettings{})
+			Ω(err).Should(BeNil())
+			Ω(resp.StatusCode).Should(Equal(http.StatusNoContent))
+			Ω(api.Requests()).Should(HaveLen(1))
+		})
+
+		It("reads credentials from environment variables on every request", func() {
+			accept("svc", "one")
+			os.Setenv("RABBITHOLE_TEST_USERNAME", "svc")
+			os.Setenv("RABBITHOLE_TEST_PASSWORD", "one")
+			defer os.Unsetenv("RABBITHOLE_TEST_USERNAME")
+			defer os.Unsetenv("RABBITHOLE_TEST_PASSWORD")
+
+			c, _ := NewClient(api.URL, "", "")
+			c.SetCredentialsProvider(NewEnvCredentialsProvider("RABBITHOLE_TEST_USERNAME", "RABBITHOLE_TEST_PASSWORD"))
+			resp, err := c.PutVhost("rabbit/hole", VhostSettings{})
+			Ω(err).Should(BeNil())
+			Ω(resp.StatusCode).Should(Equal(http.StatusNoContent))
+
+			accept("svc", "two")
+			os.Setenv("RABBITHOLE_TEST_PASSWORD", "two")
+			resp, err = c.PutVhost("rabbit/hole", VhostSettings{})
+			Ω(err).Should(BeNil())
+			Ω(resp.StatusCode).Should(Equal(http.StatusNoContent))
+			Ω(api.Requests()).Should(HaveLen(2))
+
+			os.Unsetenv("RABBITHOLE_TEST_PASSWORD")
+			_, err = c.PutVhost("rabbit/hole", VhostSettings{})
+			Ω(err).Should(HaveOccurred())
+		})
+
+		It("re-reads rotated credentials files and retries once on 401", func() {
+			accept("svc", "abc")
+			dir, err := ioutil.TempDir("", "rabbithole")
+			Ω(err).Should(BeNil())
+			defer os.RemoveAll(dir)
+			path := filepath.Join(dir, "credentials.json")
+			Ω(ioutil.WriteFile(path, []byte(`{"username":"svc","password":"abc"}`), 0600)).Should(Succeed())
+
+			c, _ := NewClient(api.URL, "", "")
+			c.SetCredentialsProvider(NewFileCredentialsProvider(path))
+			resp, err := c.PutVhost("rabbit/hole", VhostSettings{})
+			Ω(err).Should(BeNil())
+			Ω(resp.StatusCode).Should(Equal(http.StatusNoContent))
+
+			// same size and, possibly, modification time: only the 401 reveals the change
+			accept("svc", "xyz")
+			Ω(ioutil.WriteFile(path, []byte(`{"username":"svc","password":"xyz"}`), 0600)).Should(Succeed())
+			resp, err = c.PutVhost("rabbit/hole", VhostSettings{Tracing: true})
+			Ω(err).Should(BeNil())
+			Ω(resp.StatusCode).Should(Equal(http.StatusNoContent))
+
+			rs := api.Requests()
+			Ω(len(rs)).Should(BeNumerically(">=", 2))
+			// the body is sent again on retries
+			last := rs[len(rs)-1]
+			Ω(last.Password).Should(Equal("xyz"))
+			Ω(last.Body).Should(Equal(`{"tracing":true}`))
+		})
+
+		It("retries only once", func() {
+			accept("svc", "s3krE7")
+
+			c, _ := NewClient(api.URL, "", "")
+			c.SetCredentialsProvider(NewStaticCredentialsProvider("svc", "wrong"))
+			resp, err := c.PutVhost("rabbit/hole", VhostSettings{})
+			Ω(err).Should(BeNil())
+			Ω(resp.StatusCode).Should(Equal(http.StatusUnauthorized))
+			Ω(api.Requests()).Should(HaveLen(2))
+
+			_, err = c.ListVhosts()
+			Ω(err.(ErrorResponse).StatusCode).Should(Equal(http.StatusUnauthorized))
+		})
+	})
+
+	Context("middlewares", func() {
+		var api *fakeapi.Server
+
+		BeforeEach(func() {
+			api = fakeapi.New().Respond("/api/vhosts", http.StatusOK, `[]`)
+		})
+
+		AfterEach(func() {
+			api.Close()
+		})
+
+		It("runs middlewares in the order they were added", func() {
+			var order []string
+			trace := func(name string) Middleware {
+				return func(next RequestHandler) RequestHandler {
+					return func(req *http.Request) (*http.Response, error) {
+						order = append(order, name+" before")
+						res, err := next(req)
+						order = append(order, name+" after")
+						return res, err
+					}
+				}
+			}
+
+			c, _ := NewClient(api.URL, "guest", "guest")
+			c.Use(trace("a"), trace("b"))
+			_, err := c.ListVhosts()
+			Ω(err).Should(BeNil())
+			Ω(order).Should(Equal([]string{"a before", "b before", "b after", "a after"}))
+		})
+
+		It("sets request IDs and static headers", func() {
+			c, _ := NewClient(api.URL, "guest", "guest")
+			c.Use(RequestIDMiddleware(""), StaticHeadersMiddleware(map[string]string{"X-Team": "messaging"}))
+			_, err := c.ListVhosts()
+			Ω(err).Should(BeNil())
+			_, err = c.ListVhosts()
+			Ω(err).Should(BeNil())
+
+			seen := api.Requests()
+			Ω(seen).Should(HaveLen(2))
+			Ω(seen[0].Header.Get("X-Request-ID")).Should(HaveLen(32))
+			Ω(seen[0].Header.Get("X-Request-ID")).ShouldNot(Equal(seen[1].Header.Get("X-Request-ID")))
+			Ω(seen[0].Header.Get("X-Team")).Should(Equal("messaging"))
+			Ω(seen[0].Header.Get("Authorization")).Should(HavePrefix("Basic "))
+		})
+
+		It("keeps request IDs set by earlier middlewares", func() {
+			c, _ := NewClient(api.URL, "guest", "guest")
+			c.Use(StaticHeadersMiddleware(map[string]string{"X-Correlation-ID": "abc"}), RequestIDMiddleware("X-Correlation-ID"))
+			_, err := c.ListVhosts()
+			Ω(err).Should(BeNil())
+			Ω(api.Requests()[0].Header.Get("X-Correlation-ID")).Should(Equal("abc"))
+		})
+
+		It("can short-circuit requests", func() {
+			deny := func(next RequestHandler) RequestHandler {
+				return func(req *http.Request) (*http.Response, error) {
+					if req.Method == "DELETE" {
+						return nil, errors.New("deletions are not allowed")
+					}
+					return next(req)
+				}
+			}
+
+			c, _ := NewClient(api.URL, "guest", "guest")
+			c.Use(deny)
+			_, err := c.DeleteVhost("rabbit/hole")
+			Ω(err).Should(MatchError("deletions are not allowed"))
+			_, err = c.ListVhosts()
+			Ω(err).Should(BeNil())
+			Ω(api.Requests()).Should(HaveLen(1))
+		})
+	})
+
+	Context("logging", func() {
+		It("logs successful requests", func() {
+			api := fakeapi.New().Respond("/api/queues/rabbit%2Fhole", http.StatusOK, `[]`)
+			defer api.Close()
+
+			l := &recordingLogger{}
+			c, _ := NewClient(api.URL, "guest", "guest")
+			c.SetLogger(l)
+			_, err := c.ListQueuesIn("rabbit/hole")
+			Ω(err).Should(BeNil())
+
+			Ω(l.entries).Should(HaveLen(1))
+			e := l.entries[0]
+			Ω(e.level).Should(Equal("info"))
+			Ω(e.attrs["method"]).Should(Equal("GET"))
+			Ω(e.attrs["path"]).Should(Equal("/api/queues/rabbit%2Fhole"))
+			Ω(e.attrs["status"]).Should(Equal(200))
+			Ω(e.attrs).Should(HaveKey("duration"))
+		})
+
+		It("logs decoded error responses without credentials", func() {
+			api := fakeapi.New().HandleOthers(fakeapi.JSON(http.StatusBadRequest, `{"error":"bad_request","reason":"invalid user {\"name\":\"u\",\"password\":\"s3krE7\"} from guest:g5est"}`))
+			defer api.Close()
+
+			l := &recordingLogger{}
+			c, _ := NewClient(api.URL, "guest", "g5est")
+			c.SetLogger(l)
+			_, err := c.PutUser("u", UserSettings{Password: "s3krE7"})
+			Ω(err).Should(BeNil())
+			_, err = c.GetUser("u")
+			Ω(err.(ErrorResponse).Reason).Should(ContainSubstring("s3krE7"))
+
+			Ω(l.entries).Should(HaveLen(2))
+			for _, e := range l.entries {
+				Ω(e.level).Should(Equal("error"))
+				Ω(e.attrs["status"]).Should(Equal(400))
+				Ω(e.attrs["error"]).Should(Equal("bad_request"))
+				Ω(e.attrs["reason"]).Should(ContainSubstring("invalid user"))
+				Ω(fmt.Sprint(e.attrs)).ShouldNot(ContainSubstring("s3krE7"))
+				Ω(fmt.Sprint(e.attrs)).ShouldNot(ContainSubstring("g5est"))
+			}
+		})
+
+		It("logs transport errors", func() {
+			api := fakeapi.New()
+			api.Close()
+
+			l := &recordingLogger{}
+			c, _ := NewClient(api.URL, "guest", "guest")
+			c.SetLogger(l)
+			_, err := c.ListVhosts()
+			Ω(err).Should(HaveOccurred())
+			Ω(l.entries).Should(HaveLen(1))
+			Ω(l.entries[0].level).Should(Equal("error"))
+			Ω(l.entries[0].attrs).ShouldNot(HaveKey("status"))
+		})
+	})
+
+	Context("tracing", func() {
+		var (
+			api *fakeapi.Server
+			rec *SpanRecorder
+			c   *Client
+		)
+
+		BeforeEach(func() {
+			api = fakeapi.New()
+			rec = NewSpanRecorder()
+			c, _ = NewClient(api.URL, "guest", "guest")
+			c.SetTracer(rec)
+		})
+
+		AfterEach(func() {
+			api.Close()
+		})
+
+		It("creates a span per operation with a child span per request", func() {
+			api.Respond("/api/queues/rabbit%2Fhole/a.queue", http.StatusCreated, ``)
+
+			_, err := c.DeclareQueue("rabbit/hole", "a.queue", QueueSettings{Durable: true})
+			Ω(err).Should(BeNil())
+
+			spans := rec.Ended()
+			Ω(spans).Should(HaveLen(2))
+			op, req := spans[1], spans[0]
+			Ω(op.Name).Should(Equal("rabbithole.DeclareQueue"))
+			Ω(op.Parent).Should(BeNil())
+			Ω(op.Attributes).Should(Equal(map[string]interface{}{
+				SpanAttributeVhost:    "rabbit/hole",
+				SpanAttributeResource: "a.queue",
+			}))
+			Ω(req.Name).Should(Equal("HTTP PUT"))
+			Ω(req.Parent.Name).Should(Equal("rabbithole.DeclareQueue"))
+			Ω(req.Attributes).Should(Equal(map[string]interface{}{
+				SpanAttributeHTTPMethod: "PUT",
+				SpanAttributeHTTPPath:   "/api/queues/rabbit%2Fhole/a.queue",
+				SpanAttributeHTTPStatus: http.StatusCreated,
+			}))
+		})
+
+		It("records errors returned by operations", func() {
+			_, err := c.GetExchange("/", "an.exchange")
+			Ω(err).Should(Equal(ErrorResponse{404, "Object Not Found", "Not Found"}))
+
+			spans := rec.Ended()
+			Ω(spans).Should(HaveLen(2))
+			Ω(spans[1].Name).Should(Equal("rabbithole.GetExchange"))
+			Ω(spans[1].Errors).Should(Equal([]error{ErrorResponse{404, "Object Not Found", "Not Found"}}))
+			Ω(spans[0].Attributes[SpanAttributeHTTPStatus]).Should(Equal(http.StatusNotFound))
+
+			_, err = c.DeclareShovel("/", "a.shovel", ShovelDefinition{})
+			Ω(err).Should(HaveOccurred())
+			Ω(rec.Ended()[2].Name).Should(Equal("rabbithole.DeclareShovel"))
+			Ω(rec.Ended()[2].Errors).Should(Equal([]error{err}))
+		})
+
+		It("records error responses to operations that do not return them as errors", func() {
+			api.Respond("/api/queues/%2F/a.queue", http.StatusNotAcceptable,
+				`{"error":"bad_request","reason":"inequivalent arg 'durable' for queue 'a.queue'"}`)
+
+			res, err := c.DeclareQueue("/", "a.queue", QueueSettings{Durable: true})
+			Ω(err).Should(BeNil())
+			Ω(res.StatusCode).Should(Equal(http.StatusNotAcceptable))
+
+			failure := ErrorResponse{StatusCode: http.StatusNotAcceptable, Message: "Not Acceptable"}
+			spans := rec.Ended()
+			Ω(spans).Should(HaveLen(2))
+			Ω(spans[0].Name).Should(Equal("HTTP PUT"))
+			Ω(spans[0].Errors).Should(Equal([]error{failure}))
+			Ω(spans[1].Name).Should(Equal("rabbithole.DeclareQueue"))
+			Ω(spans[1].Errors).Should(Equal([]error{failure}))
+		})
+
+		It("creates a single operation span for methods delegating to other methods", func() {
+			api.Respond("/api/connections/a.connection", http.StatusNoContent, ``)
+			api.Respond("/api/queues/%2F/a.stream", http.StatusCreated, ``)
+			api.Respond("/api/overview", http.StatusOK, `{"listeners":[]}`)
+
+			_, err := c.CloseConnection("a.connection")
+			Ω(err).Should(BeNil())
+			_, err = c.DeclareStream("/", "a.stream", StreamSettings{})
+			Ω(err).Should(BeNil())
+			_, err = c.EnabledProtocols()
+			Ω(err).Should(BeNil())
+
+			var ops []string
+			for _, s := range rec.Ended() {
+				if s.Parent == nil {
+					ops = append(ops, s.Name)
+				} else {
+					Ω(s.Name).Should(HavePrefix("HTTP "))
+				}
+			}
+			Ω(ops).Should(Equal([]string{"rabbithole.CloseConnection", "rabbithole.DeclareStream", "rabbithole.EnabledProtocols"}))
+			Ω(rec.Ended()).Should(HaveLen(6))
+		})
+
+		It("nests the spans of operations used by other operations", func() {
+			api.Respond("/api/vhosts", http.StatusOK, `[{"name":"/"},{"name":"rabbit/hole"}]`)
+			api.HandleOthers(fakeapi.JSON(http.StatusOK, `{"status":"ok"}`))
+
+			_, err := c.CheckAllVhosts()
+			Ω(err).Should(BeNil())
+
+			var roots, aliveness []RecordedSpan
+			for _, s := range rec.Ended() {
+				if s.Parent == nil {
+					roots = append(roots, s)
+				}
+				if s.Name == "rabbithole.Aliveness" {
+					aliveness = append(aliveness, s)
+				}
+			}
+			Ω(roots).Should(HaveLen(1))
+			Ω(roots[0].Name).Should(Equal("rabbithole.CheckAllVhosts"))
+			Ω(aliveness).Should(HaveLen(2))
+			for _, s := range aliveness {
+				Ω(s.Parent.Name).Should(Equal("rabbithole.CheckAllVhosts"))
+			}
+			// CheckAllVhosts, ListVhosts, two aliveness tests
+			// and a request each for the latter three
+			Ω(rec.Ended()).Should(HaveLen(7))
+		})
+
+		It("uses the Client's context as the parent", func() {
+			ctx, parent := rec.StartSpan(context.Background(), "caller")
+			api.Respond("/api/vhosts", http.StatusOK, `[]`)
+
+			_, err := c.WithContext(ctx).ListVhosts()
+			Ω(err).Should(BeNil())
+			parent.End()
+
+			spans := rec.Ended()
+			Ω(spans).Should(HaveLen(3))
+			Ω(spans[1].Name).Should(Equal("rabbithole.ListVhosts"))
+			Ω(spans[1].Parent.Name).Should(Equal("caller"))
+		})
+
+		It("sets vhost and resource attributes for every kind of resource", func() {
+			api.HandleOthers(fakeapi.JSON(http.StatusOK, `[]`))
+
+			entries := []struct {
+				name     string
+				call     func() error
+				path     string
+				vhost    string
+				resource string
+			}{
+				{"DeclareQueue", func() error {
+					_, err := c.DeclareQueue("rabbit/hole", "a.queue", QueueSettings{})
+					return err
+				}, "/api/queues/rabbit%2Fhole/a.queue", "rabbit/hole", "a.queue"},
+				{"AddQuorumQueueReplica", func() error {
+					_, err := c.AddQuorumQueueReplica("rabbit/hole", "a.queue", "rabbit@node1")
+					return err
+				}, "/api/queues/quorum/rabbit%2Fhole/a.queue/replicas/add", "rabbit/hole", "a.queue"},
+				{"GrowQuorumQueueReplicas", func() error {
+					_, err := c.GrowQuorumQueueReplicas("rabbit@node1", QuorumQueueGrowSettings{Strategy: GrowAll})
+					return err
+				}, "/api/queues/quorum/replicas/on/rabbit@node1/grow", "", "rabbit@node1"},
+				{"GetExchange", func() error {
+					_, err := c.GetExchange("rabbit/hole", "an.exchange")
+					return err
+				}, "/api/exchanges/rabbit%2Fhole/an.exchange", "rabbit/hole", "an.exchange"},
+				{"DeclareBinding", func() error {
+					_, err := c.DeclareBinding("rabbit/hole", BindingInfo{Source: "amq.topic", Destination: "a.queue", DestinationType: "queue"})
+					return err
+				}, "/api/bindings/rabbit%2Fhole/e/amq.topic/q/a.queue", "rabbit/hole", "amq.topic"},
+				{"PutPolicy", func() error {
+					_, err := c.PutPolicy("rabbit/hole", "a.policy", Policy{Pattern: ".*"})
+					return err
+				}, "/api/policies/rabbit%2Fhole/a.policy", "rabbit/hole", "a.policy"},
+				{"GetPermissionsIn", func() error {
+					_, err := c.GetPermissionsIn("rabbit/hole", "a.user")
+					return err
+				}, "/api/permissions/rabbit%2Fhole/a.user", "rabbit/hole", "a.user"},
+				{"ListTopicPermissionsOf", func() error {
+					_, err := c.ListTopicPermissionsOf("a.user")
+					return err
+				}, "/api/users/a.user/topic-permissions", "", "a.user"},
+				{"GetUser", func() error {
+					_, err := c.GetUser("a.user")
+					return err
+				}, "/api/users/a.user", "", "a.user"},
+				{"DeleteUsers", func() error {
+					_, err := c.DeleteUsers([]string{"a.user"})
+					return err
+				}, "/api/users/bulk-delete", "", ""},
+				{"PutVhost", func() error {
+					_, err := c.PutVhost("rabbit/hole", VhostSettings{})
+					return err
+				}, "/api/vhosts/rabbit%2Fhole", "rabbit/hole", ""},
+				{"ListConnectionsIn", func() error {
+					_, err := c.ListConnectionsIn("rabbit/hole")
+					return err
+				}, "/api/vhosts/rabbit%2Fhole/connections", "rabbit/hole", ""},
+				{"ListConnectionsOfUser", func() error {
+					_, err := c.ListConnectionsOfUser("a.user")
+					return err
+				}, "/api/connections/username/a.user", "", "a.user"},
+				{"GetChannel", func() error {
+					_, err := c.GetChannel("a.channel")
+					return err
+				}, "/api/channels/a.channel", "", "a.channel"},
+				{"Aliveness", func() error {
+					_, err := c.Aliveness("rabbit/hole")
+					return err
+				}, "/api/aliveness-test/rabbit%2Fhole", "rabbit/hole", ""},
+				{"GetNode", func() error {
+					_, err := c.GetNode("rabbit@node1")
+					return err
+				}, "/api/nodes/rabbit@node1", "", "rabbit@node1"},
+				{"GetFederationUpstream", func() error {
+					_, err := c.GetFederationUpstream("rabbit/hole", "an.upstream")
+					return err
+				}, "/api/parameters/federation-upstream/rabbit%2Fhole/an.upstream", "rabbit/hole", "an.upstream"},
+				{"ListFederationLinksIn", func() error {
+					_, err := c.ListFederationLinksIn("rabbit/hole")
+					return err
+				}, "/api/federation-links/rabbit%2Fhole", "rabbit/hole", ""},
+				{"RestartShovel", func() error {
+					_, err := c.RestartShovel("rabbit/hole", "a.shovel")
+					return err
+				}, "/api/shovels/vhost/rabbit%2Fhole/a.shovel/restart", "rabbit/hole", "a.shovel"},
+				{"GetStreamConnection", func() error {
+					_, err := c.GetStreamConnection("rabbit/hole", "a.connection")
+					return err
+				}, "/api/stream/connections/rabbit%2Fhole/a.connection", "rabbit/hole", "a.connection"},
+				{"GetHealthCheckStatusFor", func() error {
+					_, err := c.GetHealthCheckStatusFor("rabbit@node1")
+					return err
+				}, "/api/healthchecks/node/rabbit@node1", "", "rabbit@node1"},
+				{"CheckAlarms", func() error {
+					_, err := c.CheckAlarms()
+					return err
+				}, "/api/health/checks/alarms", "", ""},
+				{"GetClusterName", func() error {
+					_, err := c.GetClusterName()
+					return err
+				}, "/api/cluster-name/", "", ""},
+				{"Whoami", func() error {
+					_, err := c.Whoami()
+					return err
+				}, "/api/whoami", "", ""},
+			}
+
+			for _, e := range entries {
+				rec.Reset()
+				// decoding errors from the canned response are fine here
+				e.call()
+
+				spans := rec.Ended()
+				Ω(spans).Should(HaveLen(2), e.name)
+				op, req := spans[1], spans[0]
+				Ω(op.Name).Should(Equal("rabbithole."+e.name), e.name)
+				Ω(req.Attributes[SpanAttributeHTTPPath]).Should(Equal(e.path), e.name)
+				Ω(req.Parent.Name).Should(Equal(op.Name), e.name)
+				if e.vhost == "" {
+					Ω(op.Attributes).ShouldNot(HaveKey(SpanAttributeVhost), e.name)
+				} else {
+					Ω(op.Attributes[SpanAttributeVhost]).Should(Equal(e.vhost), e.name)
+				}
+				if e.resource == "" {
+					Ω(op.Attributes).ShouldNot(HaveKey(SpanAttributeResource), e.name)
+				} else {
+					Ω(op.Attributes[SpanAttributeResource]).Should(Equal(e.resource), e.name)
+				}
+			}
+		})
+	})
+
+	Context("throttling", func() {
+		var api *fakeapi.Server
+
+		BeforeEach(func() {
+			api = fakeapi.New().HandleOthers(fakeapi.JSON(http.StatusOK, `[]`))
+		})
+
+		AfterEach(func() {
+			api.Close()
+		})
+
+		It("limits the request rate", func() {
+			c, _ := NewClient(api.URL, "guest", "guest")
+			c.SetRateLimit(20, 1)
+			started := time.Now()
+			for i := 0; i < 4; i++ {
+				_, err := c.ListVhosts()
+				Ω(err).Should(BeNil())
+			}
+			Ω(time.Since(started)).Should(BeNumerically(">=", 140*time.Millisecond))
+
+			stats := c.ThrottleStats()
+			Ω(stats.Requests).Should(BeEquivalentTo(4))
+			Ω(stats.Throttled).Should(BeEquivalentTo(3))
+			Ω(stats.TimeThrottled).Should(BeNumerically(">=", 100*time.Millisecond))
+		})
+
+		It("limits the number of requests in flight", func() {
+			var (
+				mu               sync.Mutex
+				current, maxSeen int
+			)
+			arrived := make(chan struct{}, 6)
+			release := make(chan struct{})
+			api.HandleOthers(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				current++
+				if current > maxSeen {
+					maxSeen = current
+				}
+				mu.Unlock()
+				arrived <- struct{}{}
+				<-release
+				mu.Lock()
+				current--
+				mu.Unlock()
+				fakeapi.JSON(http.StatusOK, `[]`)(w, r)
+			})
+
+			c, _ := NewClient(api.URL, "guest", "guest")
+			c.SetMaxInFlight(2)
+			var wg sync.WaitGroup
+			for i := 0; i < 6; i++ {
+				wg.Add(1)
+				go func() {
+					defer GinkgoRecover()
+					defer wg.Done()
+					_, err := c.ListVhosts()
+					Ω(err).Should(BeNil())
+				}()
+			}
+			Eventually(arrived).Should(Receive())
+			Eventually(arrived).Should(Receive())
+			Consistently(arrived, 50*time.Millisecond).ShouldNot(Receive())
+			Ω(c.ThrottleStats().InFlight).Should(Equal(2))
+			close(release)
+			wg.Wait()
+
+			Ω(maxSeen).Should(BeNumerically("<=", 2))
+			stats := c.ThrottleStats()
+			Ω(stats.Requests).Should(BeEquivalentTo(6))
+			Ω(stats.InFlight).Should(Equal(0))
+		})
+
+		It("counts requests as in flight until their response body is closed", func() {
+			api.Respond("/api/queues/%2F/a.queue", http.StatusCreated, ``)
+
+			c, _ := NewClient(api.URL, "guest", "guest")
+			c.SetMaxInFlight(1)
+			res, err := c.DeclareQueue("/", "a.queue", QueueSettings{})
+			Ω(err).Should(BeNil())
+			Ω(c.ThrottleStats().InFlight).Should(Equal(1))
+
+			Ω(res.Body.Close()).Should(Succeed())
+			Ω(c.ThrottleStats().InFlight).Should(Equal(0))
+
+			_, err = c.ListVhosts()
+			Ω(err).Should(BeNil())
+			Ω(c.ThrottleStats().InFlight).Should(Equal(0))
+		})
+
+		It("gives the rate token back when the context is done while waiting for a slot", func() {
+			api.Respond("/api/queues/%2F/a.queue", http.StatusCreated, ``)
+
+			c, _ := NewClient(api.URL, "guest", "guest")
+			c.SetRateLimit(0.001, 2)
+			c.SetMaxInFlight(1)
+			res, err := c.DeclareQueue("/", "a.queue", QueueSettings{})
+			Ω(err).Should(BeNil())
+
+			ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+			defer cancel()
+			_, err = c.WithContext(ctx).ListVhosts()
+			Ω(err).Should(HaveOccurred())
+			res.Body.Close()
+
+			// the second token is still available
+			ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			_, err = c.WithContext(ctx).ListVhosts()
+			Ω(err).Should(BeNil())
+			Ω(api.Requests()).Should(HaveLen(2))
+		})
+
+		It("stops waiting when the context is done", func() {
+			c, _ := NewClient(api.URL, "guest", "guest")
+			c.SetRateLimit(0.1, 1)
+			_, err := c.ListVhosts()
+			Ω(err).Should(BeNil())
+
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			started := time.Now()
+			_, err = c.WithContext(ctx).ListVhosts()
+			Ω(err).Should(HaveOccurred())
+			Ω(time.Since(started)).Should(BeNumerically("<", time.Second))
+			Ω(api.Requests()).Should(HaveLen(1))
+			Ω(c.ThrottleStats().Throttled).Should(BeEquivalentTo(1))
+		})
+
+		It("shares limits between concurrently created contexts", func() {
+			api.Respond("/api/queues/%2F/q1", http.StatusOK, `{"name":"q1","vhost":"/"}`)
+
+			c, _ := NewClient(api.URL, "guest", "guest")
+			c.SetMaxInFlight(2)
+			var wg sync.WaitGroup
+			for i := 0; i < 8; i++ {
+				wg.Add(1)
+				go func() {
+					defer GinkgoRecover()
+					defer wg.Done()
+					_, err := c.WithContext(context.Background()).GetQueue("/", "q1")
+					Ω(err).Should(BeNil())
+				}()
+			}
+			wg.Wait()
+
+			Ω(c.ThrottleStats().Requests).Should(BeEquivalentTo(8))
+		})
+
+		It("counts credential retries towards the limits", func() {
+			api.HandleOthers(fakeapi.JSON(http.StatusUnauthorized, `{"error":"not_authorised","reason":"Login failed"}`))
+
+			c, _ := NewClient(api.URL, "", "")
+			c.SetCredentialsProvider(NewStaticCredentialsProvider("svc", "wrong"))
+			c.SetRateLimit(1000, 10)
+			_, err := c.ListVhosts()
+			Ω(err).Should(HaveOccurred())
+
+			Ω(api.Requests()).Should(HaveLen(2))
+			Ω(c.ThrottleStats().Requests).Should(BeEquivalentTo(2))
+		})
+	})
 })
+
+type recordedLogEntry struct {
+	level string
+	msg   string
+	attrs map[string]interface{}
+}
+
+// records entries the way a structured logger would see them
+type recordingLogger struct {
+	entries []recordedLogEntry
+}
+
+func (l *recordingLogger) record(level, msg string, args []interface{}) {
+	e := recordedLogEntry{level: level, msg: msg, attrs: make(map[string]interface{})}
+	for i := 0; i+1 < len(args); i += 2 {
+		e.attrs[args[i].(string)] = args[i+1]
+	}
+	l.entries = append(l.entries, e)
+}
+
+func (l *recordingLogger) Info(msg string, args ...interface{}) {
+	l.record("info", msg, args)
+}
+
+func (l *recordingLogger) Error(msg string, args ...interface{}) {
+	l.record("error", msg, args)
+}
+
+// Authorization headers of the requests received by api
+func authorizationHeaders(api *fakeapi.Server) []string {
+	var xs []string
+	for _, r := range api.Requests() {
+		xs = append(xs, r.Header.Get("Authorization"))
+	}
+	return xs
+}
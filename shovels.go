@@ -2,7 +2,10 @@ package rabbithole
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
 )
 
 // ShovelInfo contains the configuration of a shovel
@@ -19,19 +22,175 @@ type ShovelInfo struct {
 
 // ShovelDefinition contains the details of the shovel configuration
 type ShovelDefinition struct {
-	SourceURI              string `json:"src-uri"`
-	SourceExchange         string `json:"src-exchange,omitempty"`
-	SourceExchangeKey      string `json:"src-exchange-key,omitempty"`
-	SourceQueue            string `json:"src-queue,omitempty"`
-	DestinationURI         string `json:"dest-uri"`
-	DestinationExchange    string `json:"dest-exchange,omitempty"`
+	// Source protocol, "amqp091" (the default) or "amqp10"
+	SourceProtocol ShovelProtocol `json:"src-protocol,omitempty"`
+	// One or more source URIs. When there is more than one, a random one is picked
+	SourceURI URISet `json:"src-uri"`
+	// AMQP 0-9-1 source queue. Mutually exclusive with SourceExchange
+	SourceQueue string `json:"src-queue,omitempty"`
+	// AMQP 0-9-1 source exchange. Mutually exclusive with SourceQueue
+	SourceExchange string `json:"src-exchange,omitempty"`
+	// Routing key used to bind to SourceExchange
+	SourceExchangeKey string `json:"src-exchange-key,omitempty"`
+	// AMQP 1.0 source address
+	SourceAddress string `json:"src-address,omitempty"`
+	// Maximum number of unacknowledged messages
+	SourcePrefetchCount int `json:"src-prefetch-count,omitempty"`
+	// When to delete the shovel: never, after transferring the messages initially in the queue,
+	// or after a number of messages
+	SourceDeleteAfter DeleteAfter `json:"src-delete-after,omitempty"`
+	// AMQP 0-9-1 consumer arguments
+	SourceConsumerArgs map[string]interface{} `json:"src-consumer-args,omitempty"`
+
+	// Destination protocol, "amqp091" (the default) or "amqp10"
+	DestinationProtocol ShovelProtocol `json:"dest-protocol,omitempty"`
+	// One or more destination URIs. When there is more than one, a random one is picked
+	DestinationURI URISet `json:"dest-uri"`
+	// AMQP 0-9-1 destination queue. Mutually exclusive with DestinationExchange
+	DestinationQueue string `json:"dest-queue,omitempty"`
+	// AMQP 0-9-1 destination exchange. Mutually exclusive with DestinationQueue
+	DestinationExchange string `json:"dest-exchange,omitempty"`
+	// Routing key to publish with, defaults to the original one
 	DestinationExchangeKey string `json:"dest-exchange-key,omitempty"`
-	DestinationQueue       string `json:"dest-queue,omitempty"`
-	PrefetchCount          int    `json:"prefetch-count,omitempty"`
-	ReconnectDelay         int    `json:"reconnect-delay,omitempty"`
-	AddForwardHeaders      bool   `json:"add-forward-headers"`
-	AckMode                string `json:"ack-mode"`
-	DeleteAfter            string `json:"delete-after"`
+	// AMQP 1.0 destination address
+	DestinationAddress string `json:"dest-address,omitempty"`
+	// Add headers describing the shovel to forwarded messages
+	DestinationAddForwardHeaders bool `json:"dest-add-forward-headers,omitempty"`
+	// Add a timestamp header to forwarded messages
+	DestinationAddTimestampHeader bool `json:"dest-add-timestamp-header,omitempty"`
+	// AMQP 0-9-1 message properties overridden on forwarded messages
+	DestinationPublishProperties map[string]interface{} `json:"dest-publish-properties,omitempty"`
+	// AMQP 1.0 message properties overridden on forwarded messages
+	DestinationProperties map[string]interface{} `json:"dest-properties,omitempty"`
+	// AMQP 1.0 application properties added to forwarded messages
+	DestinationApplicationProperties map[string]interface{} `json:"dest-application-properties,omitempty"`
+	// AMQP 1.0 message annotations added to forwarded messages
+	DestinationMessageAnnotations map[string]interface{} `json:"dest-message-annotations,omitempty"`
+
+	// Acknowledgement mode: "on-confirm", "on-publish" or "no-ack"
+	AckMode string `json:"ack-mode,omitempty"`
+	// Seconds to wait before reconnecting after a failure
+	ReconnectDelay int `json:"reconnect-delay,omitempty"`
+
+	// Deprecated: use SourcePrefetchCount
+	PrefetchCount int `json:"prefetch-count,omitempty"`
+	// Deprecated: use DestinationAddForwardHeaders
+	AddForwardHeaders bool `json:"add-forward-headers,omitempty"`
+	// Deprecated: use SourceDeleteAfter
+	DeleteAfter DeleteAfter `json:"delete-after,omitempty"`
+}
+
+// ShovelProtocol is the protocol used by either side of a shovel
+type ShovelProtocol string
+
+const (
+	ShovelAMQP091 ShovelProtocol = "amqp091"
+	ShovelAMQP10  ShovelProtocol = "amqp10"
+)
+
+// DeleteAfter is when a shovel deletes itself: "never", "queue-length"
+// or after a number of messages (see DeleteAfterMessages)
+type DeleteAfter string
+
+const (
+	DeleteAfterNever       DeleteAfter = "never"
+	DeleteAfterQueueLength DeleteAfter = "queue-length"
+)
+
+// DeleteAfterMessages returns a DeleteAfter that deletes the shovel
+// after n messages have been transferred
+func DeleteAfterMessages(n int) DeleteAfter {
+	return DeleteAfter(strconv.Itoa(n))
+}
+
+// MarshalJSON encodes a number of messages as a JSON number and anything else as a string
+func (d DeleteAfter) MarshalJSON() ([]byte, error) {
+	if n, err := strconv.Atoi(string(d)); err == nil {
+		return json.Marshal(n)
+	}
+	return json.Marshal(string(d))
+}
+
+// UnmarshalJSON accepts both a string and a number
+func (d *DeleteAfter) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*d = DeleteAfter(s)
+		return nil
+	}
+
+	var n int
+	if err := json.Unmarshal(b, &n); err != nil {
+		return err
+	}
+	*d = DeleteAfterMessages(n)
+	return nil
+}
+
+// Validate checks the shovel definition for missing, unknown or mutually exclusive options
+func (d ShovelDefinition) Validate() error {
+	if len(d.SourceURI) == 0 {
+		return errors.New("shovel source URI is required")
+	}
+	if len(d.DestinationURI) == 0 {
+		return errors.New("shovel destination URI is required")
+	}
+
+	switch d.SourceProtocol {
+	case "", ShovelAMQP091:
+		if d.SourceAddress != "" {
+			return errors.New("shovel source address is only supported by the amqp10 protocol")
+		}
+		if d.SourceQueue == "" && d.SourceExchange == "" {
+			return errors.New("shovel source queue or exchange is required")
+		}
+		if d.SourceQueue != "" && d.SourceExchange != "" {
+			return errors.New("shovel source queue and exchange are mutually exclusive")
+		}
+		if d.SourceExchangeKey != "" && d.SourceExchange == "" {
+			return errors.New("shovel source exchange key requires a source exchange")
+		}
+	case ShovelAMQP10:
+		if d.SourceAddress == "" {
+			return errors.New("shovel source address is required by the amqp10 protocol")
+		}
+		if d.SourceQueue != "" || d.SourceExchange != "" || d.SourceExchangeKey != "" {
+			return errors.New("shovel source queue and exchange are only supported by the amqp091 protocol")
+		}
+	default:
+		return fmt.Errorf("unknown shovel source protocol %q", d.SourceProtocol)
+	}
+
+	switch d.DestinationProtocol {
+	case "", ShovelAMQP091:
+		if d.DestinationAddress != "" {
+			return errors.New("shovel destination address is only supported by the amqp10 protocol")
+		}
+		if d.DestinationQueue != "" && d.DestinationExchange != "" {
+			return errors.New("shovel destination queue and exchange are mutually exclusive")
+		}
+	case ShovelAMQP10:
+		if d.DestinationAddress == "" {
+			return errors.New("shovel destination address is required by the amqp10 protocol")
+		}
+		if d.DestinationQueue != "" || d.DestinationExchange != "" || d.DestinationExchangeKey != "" {
+			return errors.New("shovel destination queue and exchange are only supported by the amqp091 protocol")
+		}
+	default:
+		return fmt.Errorf("unknown shovel destination protocol %q", d.DestinationProtocol)
+	}
+
+	for _, da := range []DeleteAfter{d.SourceDeleteAfter, d.DeleteAfter} {
+		switch da {
+		case "", DeleteAfterNever, DeleteAfterQueueLength:
+		default:
+			if n, err := strconv.Atoi(string(da)); err != nil || n < 0 {
+				return fmt.Errorf("invalid shovel delete-after value %q", da)
+			}
+		}
+	}
+
+	return nil
 }
 
 // ShovelDefinitionDTO provides a data transfer object
@@ -45,6 +204,9 @@ type ShovelDefinitionDTO struct {
 
 // ListShovels returns all shovels
 func (c *Client) ListShovels() (rec []ShovelInfo, err error) {
+	c, op := c.startOperation("ListShovels", "", "")
+	defer op.end(&err)
+
 	req, err := newGETRequest(c, "parameters/shovel")
 	if err != nil {
 		return []ShovelInfo{}, err
@@ -63,6 +225,9 @@ func (c *Client) ListShovels() (rec []ShovelInfo, err error) {
 
 // ListShovelsIn returns all shovels in a vhost
 func (c *Client) ListShovelsIn(vhost string) (rec []ShovelInfo, err error) {
+	c, op := c.startOperation("ListShovelsIn", vhost, "")
+	defer op.end(&err)
+
 	req, err := newGETRequest(c, "parameters/shovel/"+PathEscape(vhost))
 	if err != nil {
 		return []ShovelInfo{}, err
@@ -81,6 +246,9 @@ func (c *Client) ListShovelsIn(vhost string) (rec []ShovelInfo, err error) {
 
 // GetShovel returns a shovel configuration
 func (c *Client) GetShovel(vhost, shovel string) (rec *ShovelInfo, err error) {
+	c, op := c.startOperation("GetShovel", vhost, shovel)
+	defer op.end(&err)
+
 	req, err := newGETRequest(c, "parameters/shovel/"+PathEscape(vhost)+"/"+PathEscape(shovel))
 
 	if err != nil {
@@ -98,8 +266,15 @@ func (c *Client) GetShovel(vhost, shovel string) (rec *ShovelInfo, err error) {
 // PUT /api/parameters/shovel/{vhost}/{name}
 //
 
-// DeclareShovel creates a shovel
+// DeclareShovel creates a shovel. The definition is validated first
 func (c *Client) DeclareShovel(vhost, shovel string, info ShovelDefinition) (res *http.Response, err error) {
+	c, op := c.startOperation("DeclareShovel", vhost, shovel)
+	defer op.end(&err)
+
+	if err = info.Validate(); err != nil {
+		return nil, err
+	}
+
 	shovelDTO := ShovelDefinitionDTO{Definition: info}
 
 	body, err := json.Marshal(shovelDTO)
@@ -126,6 +301,9 @@ func (c *Client) DeclareShovel(vhost, shovel string, info ShovelDefinition) (res
 
 // DeleteShovel a shovel
 func (c *Client) DeleteShovel(vhost, shovel string) (res *http.Response, err error) {
+	c, op := c.startOperation("DeleteShovel", vhost, shovel)
+	defer op.end(&err)
+
 	req, err := newRequestWithBody(c, "DELETE", "parameters/shovel/"+PathEscape(vhost)+"/"+PathEscape(shovel), nil)
 	if err != nil {
 		return nil, err
@@ -138,3 +316,105 @@ func (c *Client) DeleteShovel(vhost, shovel string) (res *http.Response, err err
 
 	return res, nil
 }
+
+// ShovelState is the runtime state of a shovel
+type ShovelState string
+
+const (
+	ShovelStarting   ShovelState = "starting"
+	ShovelRunning    ShovelState = "running"
+	ShovelTerminated ShovelState = "terminated"
+)
+
+// ShovelStatus contains the runtime status of a shovel
+type ShovelStatus struct {
+	// Shovel name
+	Name string `json:"name"`
+	// Virtual host this shovel belongs to
+	Vhost string `json:"vhost"`
+	// Shovel type, "dynamic" or "static"
+	Type string `json:"type"`
+	// Shovel state: starting, running or terminated
+	State ShovelState `json:"state"`
+	// Node the shovel is running on
+	Node string `json:"node"`
+	// When the state was last updated
+	Timestamp string `json:"timestamp"`
+	// Why the shovel was terminated, if it was
+	Reason string `json:"reason,omitempty"`
+
+	SourceURI              string `json:"src_uri"`
+	SourceProtocol         string `json:"src_protocol"`
+	SourceQueue            string `json:"src_queue,omitempty"`
+	SourceExchange         string `json:"src_exchange,omitempty"`
+	SourceExchangeKey      string `json:"src_exchange_key,omitempty"`
+	DestinationURI         string `json:"dest_uri"`
+	DestinationProtocol    string `json:"dest_protocol"`
+	DestinationQueue       string `json:"dest_queue,omitempty"`
+	DestinationExchange    string `json:"dest_exchange,omitempty"`
+	DestinationExchangeKey string `json:"dest_exchange_key,omitempty"`
+}
+
+//
+// GET /api/shovels
+//
+
+// ListShovelStatus returns the status of all shovels
+func (c *Client) ListShovelStatus() (rec []ShovelStatus, err error) {
+	c, op := c.startOperation("ListShovelStatus", "", "")
+	defer op.end(&err)
+
+	req, err := newGETRequest(c, "shovels")
+	if err != nil {
+		return []ShovelStatus{}, err
+	}
+
+	if err = executeAndParseRequest(c, req, &rec); err != nil {
+		return []ShovelStatus{}, err
+	}
+
+	return rec, nil
+}
+
+//
+// GET /api/shovels/{vhost}
+//
+
+// ListShovelStatusIn returns the status of all shovels in a vhost
+func (c *Client) ListShovelStatusIn(vhost string) (rec []ShovelStatus, err error) {
+	c, op := c.startOperation("ListShovelStatusIn", vhost, "")
+	defer op.end(&err)
+
+	req, err := newGETRequest(c, "shovels/"+PathEscape(vhost))
+	if err != nil {
+		return []ShovelStatus{}, err
+	}
+
+	if err = executeAndParseRequest(c, req, &rec); err != nil {
+		return []ShovelStatus{}, err
+	}
+
+	return rec, nil
+}
+
+//
+// DELETE /api/shovels/vhost/{vhost}/{name}/restart
+//
+
+// RestartShovel restarts a dynamic shovel
+func (c *Client) RestartShovel(vhost, shovel string) (res *http.Response, err error) {
+	c, op := c.startOperation("RestartShovel", vhost, shovel)
+	defer op.end(&err)
+
+	req, err := newRequestWithBody(c, "DELETE", "shovels/vhost/"+PathEscape(vhost)+"/"+PathEscape(shovel)+"/restart", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err = executeRequest(c, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
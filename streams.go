@@ -0,0 +1,304 @@
+package rabbithole
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+)
+
+// Settings used to declare streams.
+type StreamSettings struct {
+	// Maximum size of the stream in bytes (x-max-length-bytes), 0 for no limit
+	MaxLengthBytes int64
+	// Maximum age of messages in the stream (x-max-age), e.g. "7D" or "12h".
+	// Units are Y, M, D, h, m and s. Empty for no limit
+	MaxAge string
+	// Maximum size of segment files in bytes (x-stream-max-segment-size-bytes), 0 for the default
+	MaxSegmentSizeBytes int64
+	// Number of replicas to start the stream with (x-initial-cluster-size), 0 for the default
+	InitialClusterSize int
+	// Additional arguments
+	Arguments map[string]interface{}
+}
+
+var streamMaxAgePattern = regexp.MustCompile(`^[0-9]+[YMDhms]$`)
+
+// Validate checks the stream settings for invalid values.
+func (s StreamSettings) Validate() error {
+	if s.MaxLengthBytes < 0 {
+		return errors.New("stream max length in bytes must not be negative")
+	}
+	if s.MaxAge != "" && !streamMaxAgePattern.MatchString(s.MaxAge) {
+		return fmt.Errorf("invalid stream max age %q: must be a number followed by Y, M, D, h, m or s", s.MaxAge)
+	}
+	if s.MaxSegmentSizeBytes < 0 {
+		return errors.New("stream max segment size in bytes must not be negative")
+	}
+	if s.InitialClusterSize < 0 {
+		return errors.New("stream initial cluster size must not be negative")
+	}
+
+	return nil
+}
+
+// QueueSettings returns the settings DeclareQueue uses to declare the stream.
+func (s StreamSettings) QueueSettings() QueueSettings {
+	args := make(map[string]interface{}, len(s.Arguments)+4)
+	for k, v := range s.Arguments {
+		args[k] = v
+	}
+	if s.MaxLengthBytes > 0 {
+		args["x-max-length-bytes"] = s.MaxLengthBytes
+	}
+	if s.MaxAge != "" {
+		args["x-max-age"] = s.MaxAge
+	}
+	if s.MaxSegmentSizeBytes > 0 {
+		args["x-stream-max-segment-size-bytes"] = s.MaxSegmentSizeBytes
+	}
+	if s.InitialClusterSize > 0 {
+		args["x-initial-cluster-size"] = s.InitialClusterSize
+	}
+
+	// streams are always durable
+	return QueueSettings{Type: QueueTypeStream, Durable: true, Arguments: args}
+}
+
+//
+// PUT /api/queues/{vhost}/{name}
+//
+
+// DeclareStream declares a stream. Settings are validated first.
+func (c *Client) DeclareStream(vhost, stream string, settings StreamSettings) (res *http.Response, err error) {
+	c, op := c.startOperation("DeclareStream", vhost, stream)
+	defer op.end(&err)
+
+	if err = settings.Validate(); err != nil {
+		return nil, err
+	}
+
+	return c.declareQueue(vhost, stream, settings.QueueSettings())
+}
+
+// Brief information about a stream protocol connection.
+type StreamConnectionDetails struct {
+	// Connection name
+	Name string `json:"name"`
+	// Node the client is connected to
+	Node string `json:"node"`
+	// Client host
+	PeerHost string `json:"peer_host"`
+	// Client port
+	PeerPort Port   `json:"peer_port"`
+	User     string `json:"user"`
+}
+
+// Information about a stream publisher.
+type StreamPublisher struct {
+	// Publisher identifier, unique within its connection
+	PublisherID int `json:"publisher_id"`
+	// Publisher reference, used for deduplication
+	Reference string `json:"reference"`
+	// Number of published messages
+	Published int64 `json:"published"`
+	// Number of confirmed messages
+	Confirmed int64 `json:"confirmed"`
+	// Number of messages that failed to be published
+	Errored int64 `json:"errored"`
+
+	Stream            NameAndVhost            `json:"queue"`
+	ConnectionDetails StreamConnectionDetails `json:"connection_details"`
+}
+
+// Information about a stream consumer.
+type StreamConsumer struct {
+	// Subscription identifier, unique within its connection
+	SubscriptionID int `json:"subscription_id"`
+	// Number of credits the consumer has
+	Credits int64 `json:"credits"`
+	// Number of consumed messages
+	Consumed int64 `json:"consumed"`
+	// Current offset of the consumer
+	Offset int64 `json:"offset"`
+	// Difference between the last offset in the stream and the consumer offset
+	OffsetLag int64 `json:"offset_lag"`
+	// Subscription properties
+	Properties Properties `json:"properties"`
+
+	Stream            NameAndVhost            `json:"queue"`
+	ConnectionDetails StreamConnectionDetails `json:"connection_details"`
+}
+
+//
+// GET /api/stream/connections
+//
+
+// Returns information about all stream protocol connections.
+func (c *Client) ListStreamConnections() (rec []ConnectionInfo, err error) {
+	c, op := c.startOperation("ListStreamConnections", "", "")
+	defer op.end(&err)
+
+	req, err := newGETRequest(c, "stream/connections")
+	if err != nil {
+		return []ConnectionInfo{}, err
+	}
+
+	if err = executeAndParseRequest(c, req, &rec); err != nil {
+		return []ConnectionInfo{}, err
+	}
+
+	return rec, nil
+}
+
+//
+// GET /api/stream/connections/{vhost}
+//
+
+// Returns information about stream protocol connections in a virtual host.
+func (c *Client) ListStreamConnectionsIn(vhost string) (rec []ConnectionInfo, err error) {
+	c, op := c.startOperation("ListStreamConnectionsIn", vhost, "")
+	defer op.end(&err)
+
+	req, err := newGETRequest(c, "stream/connections/"+PathEscape(vhost))
+	if err != nil {
+		return []ConnectionInfo{}, err
+	}
+
+	if err = executeAndParseRequest(c, req, &rec); err != nil {
+		return []ConnectionInfo{}, err
+	}
+
+	return rec, nil
+}
+
+//
+// GET /api/stream/connections/{vhost}/{name}
+//
+
+// Returns information about a stream protocol connection.
+func (c *Client) GetStreamConnection(vhost, name string) (rec *ConnectionInfo, err error) {
+	c, op := c.startOperation("GetStreamConnection", vhost, name)
+	defer op.end(&err)
+
+	req, err := newGETRequest(c, "stream/connections/"+PathEscape(vhost)+"/"+PathEscape(name))
+	if err != nil {
+		return nil, err
+	}
+
+	if err = executeAndParseRequest(c, req, &rec); err != nil {
+		return nil, err
+	}
+
+	return rec, nil
+}
+
+//
+// GET /api/stream/connections/{vhost}/{name}/publishers
+//
+
+// Returns publishers of a stream protocol connection.
+func (c *Client) ListStreamConnectionPublishers(vhost, name string) (rec []StreamPublisher, err error) {
+	c, op := c.startOperation("ListStreamConnectionPublishers", vhost, name)
+	defer op.end(&err)
+
+	return c.listStreamPublishers("stream/connections/" + PathEscape(vhost) + "/" + PathEscape(name) + "/publishers")
+}
+
+//
+// GET /api/stream/connections/{vhost}/{name}/consumers
+//
+
+// Returns consumers of a stream protocol connection.
+func (c *Client) ListStreamConnectionConsumers(vhost, name string) (rec []StreamConsumer, err error) {
+	c, op := c.startOperation("ListStreamConnectionConsumers", vhost, name)
+	defer op.end(&err)
+
+	return c.listStreamConsumers("stream/connections/" + PathEscape(vhost) + "/" + PathEscape(name) + "/consumers")
+}
+
+//
+// GET /api/stream/publishers
+//
+
+// Returns all stream publishers.
+func (c *Client) ListStreamPublishers() (rec []StreamPublisher, err error) {
+	c, op := c.startOperation("ListStreamPublishers", "", "")
+	defer op.end(&err)
+
+	return c.listStreamPublishers("stream/publishers")
+}
+
+//
+// GET /api/stream/publishers/{vhost}
+//
+
+// Returns stream publishers in a virtual host.
+func (c *Client) ListStreamPublishersIn(vhost string) (rec []StreamPublisher, err error) {
+	c, op := c.startOperation("ListStreamPublishersIn", vhost, "")
+	defer op.end(&err)
+
+	return c.listStreamPublishers("stream/publishers/" + PathEscape(vhost))
+}
+
+//
+// GET /api/stream/publishers/{vhost}/{stream}
+//
+
+// Returns publishers of a stream.
+func (c *Client) ListStreamPublishersOf(vhost, stream string) (rec []StreamPublisher, err error) {
+	c, op := c.startOperation("ListStreamPublishersOf", vhost, stream)
+	defer op.end(&err)
+
+	return c.listStreamPublishers("stream/publishers/" + PathEscape(vhost) + "/" + PathEscape(stream))
+}
+
+//
+// GET /api/stream/consumers
+//
+
+// Returns all stream consumers.
+func (c *Client) ListStreamConsumers() (rec []StreamConsumer, err error) {
+	c, op := c.startOperation("ListStreamConsumers", "", "")
+	defer op.end(&err)
+
+	return c.listStreamConsumers("stream/consumers")
+}
+
+//
+// GET /api/stream/consumers/{vhost}
+//
+
+// Returns stream consumers in a virtual host.
+func (c *Client) ListStreamConsumersIn(vhost string) (rec []StreamConsumer, err error) {
+	c, op := c.startOperation("ListStreamConsumersIn", vhost, "")
+	defer op.end(&err)
+
+	return c.listStreamConsumers("stream/consumers/" + PathEscape(vhost))
+}
+
+func (c *Client) listStreamPublishers(path string) (rec []StreamPublisher, err error) {
+	req, err := newGETRequest(c, path)
+	if err != nil {
+		return []StreamPublisher{}, err
+	}
+
+	if err = executeAndParseRequest(c, req, &rec); err != nil {
+		return []StreamPublisher{}, err
+	}
+
+	return rec, nil
+}
+
+func (c *Client) listStreamConsumers(path string) (rec []StreamConsumer, err error) {
+	req, err := newGETRequest(c, path)
+	if err != nil {
+		return []StreamConsumer{}, err
+	}
+
+	if err = executeAndParseRequest(c, req, &rec); err != nil {
+		return []StreamConsumer{}, err
+	}
+
+	return rec, nil
+}
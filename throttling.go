@@ -0,0 +1,218 @@
+package rabbithole
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ThrottleStats reports how much requests were delayed by the limits
+// set with SetRateLimit and SetMaxInFlight.
+type ThrottleStats struct {
+	// Requests that went through the limits
+	Requests uint64
+	// Requests that had to wait
+	Throttled uint64
+	// Total time requests spent waiting
+	TimeThrottled time.Duration
+	// Requests currently being sent
+	InFlight int
+}
+
+type throttle struct {
+	mu sync.Mutex
+
+	// token bucket, rate is in tokens per second
+	rate   float64
+	burst  int
+	tokens float64
+	last   time.Time
+
+	inFlight chan struct{}
+	stats    ThrottleStats
+}
+
+// SetRateLimit limits the Client to rate requests per second on average,
+// with bursts of up to burst requests. Requests over the limit wait, unless
+// the Client's context (see WithContext) is done first. A rate of zero
+// removes the limit. Limits are only available to Clients created with
+// NewClient or NewTLSClient.
+func (c *Client) SetRateLimit(rate float64, burst int) {
+	t := c.throttle
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if burst < 1 {
+		burst = 1
+	}
+	t.rate, t.burst = rate, burst
+	t.tokens, t.last = float64(burst), time.Now()
+}
+
+// SetMaxInFlight limits the number of requests the Client sends concurrently.
+// A request counts as in flight until its response body has been read to the end
+// or closed, so the bodies of responses returned by methods such as DeclareQueue
+// must be closed. Zero removes the limit.
+func (c *Client) SetMaxInFlight(n int) {
+	t := c.throttle
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if n <= 0 {
+		t.inFlight = nil
+		return
+	}
+	t.inFlight = make(chan struct{}, n)
+}
+
+// ThrottleStats returns statistics about requests delayed by the limits
+// set with SetRateLimit and SetMaxInFlight.
+func (c *Client) ThrottleStats() ThrottleStats {
+	if c.throttle == nil {
+		return ThrottleStats{}
+	}
+
+	c.throttle.mu.Lock()
+	defer c.throttle.mu.Unlock()
+
+	s := c.throttle.stats
+	if c.throttle.inFlight != nil {
+		s.InFlight = len(c.throttle.inFlight)
+	}
+	return s
+}
+
+// Takes a token from the bucket, returning whether there was a limit
+// and how long to wait for the token.
+func (t *throttle) reserve() (bool, time.Duration) {
+	if t.rate <= 0 {
+		return false, 0
+	}
+
+	now := time.Now()
+	t.tokens += now.Sub(t.last).Seconds() * t.rate
+	if t.tokens > float64(t.burst) {
+		t.tokens = float64(t.burst)
+	}
+	t.last = now
+
+	t.tokens--
+	if t.tokens >= 0 {
+		return true, 0
+	}
+	return true, time.Duration(-t.tokens / t.rate * float64(time.Second))
+}
+
+// Gives back a token taken by reserve.
+func (t *throttle) unreserve() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.tokens++
+}
+
+func (t *throttle) wait(ctx context.Context) (release func(), err error) {
+	started := time.Now()
+	throttled := false
+	defer func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		t.stats.Requests++
+		if throttled {
+			t.stats.Throttled++
+			t.stats.TimeThrottled += time.Since(started)
+		}
+	}()
+
+	t.mu.Lock()
+	reserved, delay := t.reserve()
+	inFlight := t.inFlight
+	t.mu.Unlock()
+
+	if delay > 0 {
+		throttled = true
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			t.unreserve()
+			return nil, ctx.Err()
+		}
+	}
+
+	if inFlight == nil {
+		return func() {}, nil
+	}
+	select {
+	case inFlight <- struct{}{}:
+		return func() { <-inFlight }, nil
+	default:
+	}
+
+	throttled = true
+	select {
+	case inFlight <- struct{}{}:
+		return func() { <-inFlight }, nil
+	case <-ctx.Done():
+		if reserved {
+			t.unreserve()
+		}
+		return nil, ctx.Err()
+	}
+}
+
+func throttlingMiddleware(t *throttle) Middleware {
+	return func(next RequestHandler) RequestHandler {
+		return func(req *http.Request) (*http.Response, error) {
+			release, err := t.wait(req.Context())
+			if err != nil {
+				return nil, err
+			}
+
+			res, err := next(req)
+			if err != nil {
+				release()
+				return nil, err
+			}
+			res.Body = &releasingBody{ReadCloser: res.Body, release: release}
+
+			return res, nil
+		}
+	}
+}
+
+// A response body that frees the in-flight slot of its request
+// once it has been read to the end or closed.
+type releasingBody struct {
+	io.ReadCloser
+
+	once    sync.Once
+	release func()
+}
+
+func (b *releasingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err == io.EOF {
+		b.once.Do(b.release)
+	}
+	return n, err
+}
+
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
+
+// WithContext returns a copy of the Client whose requests use ctx:
+// they are cancelled, including while waiting for rate limits,
+// when ctx is done. The copy shares limits and statistics with c.
+// It is safe to call concurrently.
+func (c *Client) WithContext(ctx context.Context) *Client {
+	cc := *c
+	cc.ctx = ctx
+	return &cc
+}
@@ -0,0 +1,144 @@
+package rabbithole
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Tokens are refreshed this long before they expire, so that they
+// do not expire while a request is in flight.
+const tokenExpiryDelta = 10 * time.Second
+
+// Token is a bearer token, e.g. an OAuth 2 access token.
+type Token struct {
+	AccessToken string
+	// Zero if the token never expires
+	Expiry time.Time
+}
+
+// Valid returns true if the token is set and is not about to expire.
+func (t *Token) Valid() bool {
+	if t == nil || t.AccessToken == "" {
+		return false
+	}
+	return t.Expiry.IsZero() || time.Now().Add(tokenExpiryDelta).Before(t.Expiry)
+}
+
+// TokenSource provides bearer tokens used by Client.SetTokenSource.
+type TokenSource interface {
+	Token() (*Token, error)
+}
+
+// StaticTokenSource always returns the same token.
+type StaticTokenSource string
+
+func (s StaticTokenSource) Token() (*Token, error) {
+	return &Token{AccessToken: string(s)}, nil
+}
+
+type reuseTokenSource struct {
+	mu  sync.Mutex
+	src TokenSource
+	t   *Token
+}
+
+// ReuseTokenSource returns a TokenSource that caches tokens from src
+// and only asks it for a new one when the cached token is about to expire.
+func ReuseTokenSource(src TokenSource) TokenSource {
+	if rs, ok := src.(*reuseTokenSource); ok {
+		return rs
+	}
+	return &reuseTokenSource{src: src}
+}
+
+func (s *reuseTokenSource) Token() (*Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.t.Valid() {
+		return s.t, nil
+	}
+
+	t, err := s.src.Token()
+	if err != nil {
+		return nil, err
+	}
+	s.t = t
+
+	return t, nil
+}
+
+// ClientCredentialsTokenSource obtains access tokens from an OAuth 2
+// authorization server (e.g. UAA or Keycloak) using the client credentials grant.
+// Every call requests a new token; use it with Client.SetTokenSource or
+// ReuseTokenSource to reuse tokens until they expire.
+type ClientCredentialsTokenSource struct {
+	// Token endpoint of the authorization server
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+	// Transport used to reach the token endpoint. Uses http.DefaultTransport if nil
+	Transport http.RoundTripper
+	// HTTP timeout. By default there is no timeout
+	Timeout time.Duration
+}
+
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+func (s *ClientCredentialsTokenSource) Token() (*Token, error) {
+	form := url.Values{}
+	form.Set("grant_type", "client_credentials")
+	form.Set("client_id", s.ClientID)
+	form.Set("client_secret", s.ClientSecret)
+	if len(s.Scopes) > 0 {
+		form.Set("scope", strings.Join(s.Scopes, " "))
+	}
+
+	req, err := http.NewRequest("POST", s.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	httpc := &http.Client{Transport: s.Transport, Timeout: s.Timeout}
+	res, err := httpc.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	var tr tokenResponse
+	if err = json.NewDecoder(res.Body).Decode(&tr); err != nil && res.StatusCode < http.StatusBadRequest {
+		return nil, err
+	}
+	if res.StatusCode >= http.StatusBadRequest || tr.Error != "" {
+		return nil, fmt.Errorf("token request failed with status %d: %s %s", res.StatusCode, tr.Error, tr.ErrorDescription)
+	}
+	if tr.AccessToken == "" {
+		return nil, fmt.Errorf("token response from %s does not contain an access token", s.TokenURL)
+	}
+	if tr.TokenType != "" && !strings.EqualFold(tr.TokenType, "bearer") {
+		return nil, fmt.Errorf("unsupported token type %q", tr.TokenType)
+	}
+
+	t := &Token{AccessToken: tr.AccessToken}
+	if tr.ExpiresIn > 0 {
+		t.Expiry = time.Now().Add(time.Duration(tr.ExpiresIn) * time.Second)
+	}
+
+	return t, nil
+}
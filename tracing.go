@@ -0,0 +1,227 @@
+package rabbithole
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Span attribute keys
+const (
+	SpanAttributeVhost      = "rabbitmq.vhost"
+	SpanAttributeResource   = "rabbitmq.resource"
+	SpanAttributeHTTPMethod = "http.method"
+	SpanAttributeHTTPPath   = "http.path"
+	SpanAttributeHTTPStatus = "http.status_code"
+)
+
+// Tracer starts spans. Its shape follows OpenTelemetry's, so an adapter
+// to an OpenTelemetry (or any other) tracer only takes a few lines.
+type Tracer interface {
+	// StartSpan starts a span that is a child of the span in ctx, if any,
+	// and returns a context that carries the new span.
+	StartSpan(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span is a traced client operation or HTTP request.
+type Span interface {
+	SetAttribute(key string, value interface{})
+	RecordError(err error)
+	End()
+}
+
+// SetTracer makes the Client create a span for every operation, named after
+// the Client method called, e.g. rabbithole.DeclareQueue, with the virtual host
+// and the name of the resource operated on as attributes. Every HTTP request
+// an operation sends gets a child span. Operations that use other operations,
+// such as CheckAllVhosts, have their spans as children. Error responses are
+// recorded as errors of both spans, including for methods such as DeclareQueue
+// that return them without an error.
+// Pass nil to disable tracing.
+func (c *Client) SetTracer(t Tracer) {
+	c.tracer = t
+}
+
+// A client operation traced by a span.
+type operation struct {
+	span Span
+
+	mu sync.Mutex
+	// error response to the last request of the operation, if any
+	failure error
+}
+
+type operationKey struct{}
+
+// Starts the span of a client operation. The returned Client is a copy of c
+// whose requests are children of that span. Without a tracer, c is returned
+// along with a nil operation.
+func (c *Client) startOperation(name, vhost, resource string) (*Client, *operation) {
+	if c.tracer == nil {
+		return c, nil
+	}
+
+	ctx := c.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, span := c.tracer.StartSpan(ctx, "rabbithole."+name)
+	op := &operation{span: span}
+	ctx = context.WithValue(ctx, operationKey{}, op)
+	if vhost != "" {
+		span.SetAttribute(SpanAttributeVhost, vhost)
+	}
+	if resource != "" {
+		span.SetAttribute(SpanAttributeResource, resource)
+	}
+
+	cc := *c
+	cc.ctx = ctx
+	return &cc, op
+}
+
+// Ends the span, recording the error the operation returned, if any.
+// Operations that return error responses without an error, such as
+// DeclareQueue, have the error response recorded instead.
+func (op *operation) end(err *error) {
+	if op == nil {
+		return
+	}
+	op.mu.Lock()
+	failure := op.failure
+	op.mu.Unlock()
+
+	if *err != nil {
+		op.span.RecordError(*err)
+	} else if failure != nil {
+		op.span.RecordError(failure)
+	}
+	op.span.End()
+}
+
+func (op *operation) responded(failure error) {
+	op.mu.Lock()
+	defer op.mu.Unlock()
+
+	op.failure = failure
+}
+
+// An error describing responses with a 4xx or 5xx status, nil otherwise.
+func responseFailure(res *http.Response) error {
+	if res.StatusCode < http.StatusBadRequest {
+		return nil
+	}
+	return ErrorResponse{StatusCode: res.StatusCode, Message: http.StatusText(res.StatusCode)}
+}
+
+func tracingMiddleware(t Tracer) Middleware {
+	return func(next RequestHandler) RequestHandler {
+		return func(req *http.Request) (*http.Response, error) {
+			ctx, span := t.StartSpan(req.Context(), "HTTP "+req.Method)
+			defer span.End()
+
+			span.SetAttribute(SpanAttributeHTTPMethod, req.Method)
+			span.SetAttribute(SpanAttributeHTTPPath, requestPath(req))
+
+			res, err := next(req.WithContext(ctx))
+			if err != nil {
+				span.RecordError(err)
+				return res, err
+			}
+			span.SetAttribute(SpanAttributeHTTPStatus, res.StatusCode)
+
+			failure := responseFailure(res)
+			if failure != nil {
+				span.RecordError(failure)
+			}
+			if op, ok := req.Context().Value(operationKey{}).(*operation); ok {
+				op.responded(failure)
+			}
+
+			return res, nil
+		}
+	}
+}
+
+// RecordedSpan is a span recorded by a SpanRecorder.
+type RecordedSpan struct {
+	Name       string
+	Attributes map[string]interface{}
+	Errors     []error
+	Parent     *RecordedSpan
+	Started    time.Time
+	Ended      time.Time
+}
+
+// SpanRecorder is a Tracer that keeps spans in memory, e.g. for tests.
+type SpanRecorder struct {
+	mu    sync.Mutex
+	spans []*RecordedSpan
+}
+
+// NewSpanRecorder returns an empty SpanRecorder.
+func NewSpanRecorder() *SpanRecorder {
+	return &SpanRecorder{}
+}
+
+type spanRecorderKey struct{}
+
+func (r *SpanRecorder) StartSpan(ctx context.Context, name string) (context.Context, Span) {
+	s := &recorderSpan{
+		recorder: r,
+		span:     &RecordedSpan{Name: name, Attributes: make(map[string]interface{}), Started: time.Now()},
+	}
+	if parent, ok := ctx.Value(spanRecorderKey{}).(*recorderSpan); ok {
+		s.span.Parent = parent.span
+	}
+
+	return context.WithValue(ctx, spanRecorderKey{}, s), s
+}
+
+// Ended returns spans that have ended, in the order they ended.
+func (r *SpanRecorder) Ended() []RecordedSpan {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	xs := make([]RecordedSpan, len(r.spans))
+	for i, s := range r.spans {
+		xs[i] = *s
+	}
+	return xs
+}
+
+// Reset discards recorded spans.
+func (r *SpanRecorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.spans = nil
+}
+
+type recorderSpan struct {
+	recorder *SpanRecorder
+	span     *RecordedSpan
+}
+
+func (s *recorderSpan) SetAttribute(key string, value interface{}) {
+	s.recorder.mu.Lock()
+	defer s.recorder.mu.Unlock()
+
+	s.span.Attributes[key] = value
+}
+
+func (s *recorderSpan) RecordError(err error) {
+	s.recorder.mu.Lock()
+	defer s.recorder.mu.Unlock()
+
+	s.span.Errors = append(s.span.Errors, err)
+}
+
+func (s *recorderSpan) End() {
+	s.recorder.mu.Lock()
+	defer s.recorder.mu.Unlock()
+
+	s.span.Ended = time.Now()
+	s.recorder.spans = append(s.recorder.spans, s.span)
+}
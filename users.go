@@ -3,11 +3,14 @@ package rabbithole
 import (
 	"encoding/json"
 	"net/http"
+	"sort"
 )
 
 type UserInfo struct {
 	Name         string `json:"name"`
 	PasswordHash string `json:"password_hash"`
+	// Algorithm used to compute PasswordHash
+	HashingAlgorithm HashingAlgorithm `json:"hashing_algorithm,omitempty"`
 	// Tags control permissions. Built-in tags: administrator, management, policymaker.
 	Tags string `json:"tags"`
 }
@@ -24,6 +27,9 @@ type UserSettings struct {
 	// to create/update a user. MK.
 	Password     string `json:"password,omitempty"`
 	PasswordHash string `json:"password_hash,omitempty"`
+	// Algorithm PasswordHash was computed with, see HashPassword.
+	// RabbitMQ assumes its configured default when not set.
+	HashingAlgorithm HashingAlgorithm `json:"hashing_algorithm,omitempty"`
 }
 
 //
@@ -35,6 +41,9 @@ type UserSettings struct {
 
 // Returns a list of all users in a cluster.
 func (c *Client) ListUsers() (rec []UserInfo, err error) {
+	c, op := c.startOperation("ListUsers", "", "")
+	defer op.end(&err)
+
 	req, err := newGETRequest(c, "users/")
 	if err != nil {
 		return []UserInfo{}, err
@@ -47,12 +56,87 @@ func (c *Client) ListUsers() (rec []UserInfo, err error) {
 	return rec, nil
 }
 
+//
+// GET /api/users/without-permissions
+//
+
+// Returns users that do not have access to any virtual host.
+func (c *Client) ListUsersWithoutPermissions() (rec []UserInfo, err error) {
+	c, op := c.startOperation("ListUsersWithoutPermissions", "", "")
+	defer op.end(&err)
+
+	req, err := newGETRequest(c, "users/without-permissions")
+	if err != nil {
+		return []UserInfo{}, err
+	}
+
+	if err = executeAndParseRequest(c, req, &rec); err != nil {
+		return []UserInfo{}, err
+	}
+
+	return rec, nil
+}
+
+// UserVhostAccess lists the virtual hosts a user has permissions in.
+type UserVhostAccess struct {
+	Name string
+	Tags string
+	// Virtual hosts the user has permissions in, sorted
+	Vhosts []string
+}
+
+// Returns true if the user has permissions in at least one virtual host.
+func (a UserVhostAccess) HasVhostAccess() bool {
+	return len(a.Vhosts) > 0
+}
+
+// Returns every user along with the virtual hosts it has permissions in,
+// by combining ListUsers and ListPermissions. Users without access to any
+// virtual host are candidates for cleanup.
+func (c *Client) ListUserVhostAccess() (rec []UserVhostAccess, err error) {
+	c, op := c.startOperation("ListUserVhostAccess", "", "")
+	defer op.end(&err)
+
+	users, err := c.ListUsers()
+	if err != nil {
+		return []UserVhostAccess{}, err
+	}
+
+	permissions, err := c.ListPermissions()
+	if err != nil {
+		return []UserVhostAccess{}, err
+	}
+
+	return joinUserVhostAccess(users, permissions), nil
+}
+
+func joinUserVhostAccess(users []UserInfo, permissions []PermissionInfo) []UserVhostAccess {
+	vhosts := make(map[string][]string)
+	for _, p := range permissions {
+		vhosts[p.User] = append(vhosts[p.User], p.Vhost)
+	}
+
+	xs := make([]UserVhostAccess, 0, len(users))
+	for _, u := range users {
+		vs := vhosts[u.Name]
+		sort.Strings(vs)
+		xs = append(xs, UserVhostAccess{Name: u.Name, Tags: u.Tags, Vhosts: vs})
+	}
+
+	sort.Slice(xs, func(i, j int) bool { return xs[i].Name < xs[j].Name })
+
+	return xs
+}
+
 //
 // GET /api/users/{name}
 //
 
 // Returns information about individual user.
 func (c *Client) GetUser(username string) (rec *UserInfo, err error) {
+	c, op := c.startOperation("GetUser", "", username)
+	defer op.end(&err)
+
 	req, err := newGETRequest(c, "users/"+PathEscape(username))
 	if err != nil {
 		return nil, err
@@ -71,6 +155,9 @@ func (c *Client) GetUser(username string) (rec *UserInfo, err error) {
 
 // Updates information about individual user.
 func (c *Client) PutUser(username string, info UserSettings) (res *http.Response, err error) {
+	c, op := c.startOperation("PutUser", "", username)
+	defer op.end(&err)
+
 	body, err := json.Marshal(info)
 	if err != nil {
 		return nil, err
@@ -90,6 +177,9 @@ func (c *Client) PutUser(username string, info UserSettings) (res *http.Response
 }
 
 func (c *Client) PutUserWithoutPassword(username string, info UserSettings) (res *http.Response, err error) {
+	c, op := c.startOperation("PutUserWithoutPassword", "", username)
+	defer op.end(&err)
+
 	body, err := json.Marshal(UserInfo{Tags: info.Tags})
 	if err != nil {
 		return nil, err
@@ -114,6 +204,9 @@ func (c *Client) PutUserWithoutPassword(username string, info UserSettings) (res
 
 // Deletes user.
 func (c *Client) DeleteUser(username string) (res *http.Response, err error) {
+	c, op := c.startOperation("DeleteUser", "", username)
+	defer op.end(&err)
+
 	req, err := newRequestWithBody(c, "DELETE", "users/"+PathEscape(username), nil)
 	if err != nil {
 		return nil, err
@@ -126,3 +219,34 @@ func (c *Client) DeleteUser(username string) (res *http.Response, err error) {
 
 	return res, nil
 }
+
+//
+// POST /api/users/bulk-delete
+//
+
+type usersBulkDelete struct {
+	Users []string `json:"users"`
+}
+
+// Deletes multiple users in one request.
+func (c *Client) DeleteUsers(usernames []string) (res *http.Response, err error) {
+	c, op := c.startOperation("DeleteUsers", "", "")
+	defer op.end(&err)
+
+	body, err := json.Marshal(usersBulkDelete{Users: usernames})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := newRequestWithBody(c, "POST", "users/bulk-delete", body)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err = executeRequest(c, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
@@ -79,6 +79,9 @@ type VhostInfo struct {
 
 // Returns a list of virtual hosts.
 func (c *Client) ListVhosts() (rec []VhostInfo, err error) {
+	c, op := c.startOperation("ListVhosts", "", "")
+	defer op.end(&err)
+
 	req, err := newGETRequest(c, "vhosts")
 	if err != nil {
 		return []VhostInfo{}, err
@@ -97,6 +100,9 @@ func (c *Client) ListVhosts() (rec []VhostInfo, err error) {
 
 // Returns information about a specific virtual host.
 func (c *Client) GetVhost(vhostname string) (rec *VhostInfo, err error) {
+	c, op := c.startOperation("GetVhost", vhostname, "")
+	defer op.end(&err)
+
 	req, err := newGETRequest(c, "vhosts/"+PathEscape(vhostname))
 	if err != nil {
 		return nil, err
@@ -121,6 +127,9 @@ type VhostSettings struct {
 
 // Creates or updates a virtual host.
 func (c *Client) PutVhost(vhostname string, settings VhostSettings) (res *http.Response, err error) {
+	c, op := c.startOperation("PutVhost", vhostname, "")
+	defer op.end(&err)
+
 	body, err := json.Marshal(settings)
 	if err != nil {
 		return nil, err
@@ -145,6 +154,9 @@ func (c *Client) PutVhost(vhostname string, settings VhostSettings) (res *http.R
 
 // Deletes a virtual host.
 func (c *Client) DeleteVhost(vhostname string) (res *http.Response, err error) {
+	c, op := c.startOperation("DeleteVhost", vhostname, "")
+	defer op.end(&err)
+
 	req, err := newRequestWithBody(c, "DELETE", "vhosts/"+PathEscape(vhostname), nil)
 	if err != nil {
 		return nil, err